@@ -0,0 +1,135 @@
+package melhorenvio
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+const earthRadiusKm = 6371.0
+
+type AgencyFilter struct {
+	// id da transportadora (Company.ID)
+	Company int32
+	Country string
+	// sigla do estado, ex: "SP"
+	State string
+	City  string
+}
+
+func (f *AgencyFilter) query() url.Values {
+	query := url.Values{}
+	if f == nil {
+		return query
+	}
+
+	if f.Company != 0 {
+		query.Set("company", strconv.FormatInt(int64(f.Company), 10))
+	}
+	if f.Country != "" {
+		query.Set("country", f.Country)
+	}
+	if f.State != "" {
+		query.Set("state", f.State)
+	}
+	if f.City != "" {
+		query.Set("city", f.City)
+	}
+	return query
+}
+
+type AgencyState struct {
+	Id        int32  `json:"id"`
+	State     string `json:"state"`
+	StateAbbr string `json:"state_abbr"`
+}
+
+type AgencyCity struct {
+	Id    int32       `json:"id"`
+	City  string      `json:"city"`
+	State AgencyState `json:"state"`
+}
+
+type AgencyAddress struct {
+	Id         int32      `json:"id"`
+	Label      string     `json:"label"`
+	PostalCode string     `json:"postal_code"`
+	Address    string     `json:"address"`
+	Number     string     `json:"number"`
+	Complement string     `json:"complement"`
+	District   string     `json:"district"`
+	Latitude   float64    `json:"latitude"`
+	Longitude  float64    `json:"longitude"`
+	City       AgencyCity `json:"city"`
+}
+
+type AgencyPhone struct {
+	Id      int32  `json:"id"`
+	Label   string `json:"label"`
+	Phone   string `json:"phone"`
+	Country string `json:"country_id"`
+}
+
+type Agency struct {
+	ID        int32         `json:"id"`
+	Name      string        `json:"name"`
+	Initials  string        `json:"initials"`
+	Code      string        `json:"code"`
+	CompanyId int32         `json:"company_id"`
+	Status    Status        `json:"status"`
+	Email     string        `json:"email"`
+	Note      string        `json:"note"`
+	Address   AgencyAddress `json:"address"`
+	Phone     AgencyPhone   `json:"phone"`
+}
+
+// ListAgencies lista as agências (pontos de postagem), utilizadas em AddToCartRequest.Agency
+func (c *Client) ListAgencies(ctx context.Context, filter *AgencyFilter) ([]*Agency, error) {
+	path := "/api/v2/me/shipment/agencies"
+	if query := filter.query(); len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, _, _, err := getPublic[[]*Agency](ctx, c, path, "")
+	return resp, err
+}
+
+// NearestAgency retorna a agência mais próxima das coordenadas informadas e a distância em km
+// agências sem coordenadas são ignoradas
+func NearestAgency(agencies []*Agency, latitude float64, longitude float64) (*Agency, float64) {
+	var nearest *Agency
+	nearestDistance := math.Inf(1)
+
+	for _, agency := range agencies {
+		if agency == nil || (agency.Address.Latitude == 0 && agency.Address.Longitude == 0) {
+			continue
+		}
+
+		distance := HaversineDistance(latitude, longitude, agency.Address.Latitude, agency.Address.Longitude)
+		if distance < nearestDistance {
+			nearest = agency
+			nearestDistance = distance
+		}
+	}
+
+	if nearest == nil {
+		return nil, 0
+	}
+	return nearest, nearestDistance
+}
+
+// HaversineDistance retorna a distância em km entre duas coordenadas
+func HaversineDistance(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64 {
+	toRad := func(degrees float64) float64 {
+		return degrees * math.Pi / 180
+	}
+
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
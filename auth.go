@@ -1,8 +1,9 @@
 package melhorenvio
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -32,87 +33,161 @@ type authResponse struct {
 }
 
 func (c *Client) AutenticateByCode() error {
+	return c.AutenticateByCodeContext(c.context)
+}
+
+func (c *Client) AutenticateByCodeContext(ctx context.Context) error {
 	if !c.initialized {
 		return ErrClientNotInitialized
 	}
 
-	if c.config.Credentials.Code == "" {
+	code := c.Credentials().Code
+	if code == "" {
 		return ErrInvalidToken
 	}
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	return c.singleFlight(ctx, flightCode+":"+code, func(ctx context.Context) error {
+		unlock, err := c.lockStore(ctx)
+		if err != nil {
+			return err
+		}
+		defer unlock()
 
-	buf := &bytes.Buffer{}
-	aReq := &authRequest{
+		credentials, err := c.ExchangeCode(ctx, code)
+		if err != nil {
+			return err
+		}
+
+		return c.applyCredentials(ctx, credentials, code)
+	})
+}
+
+// ExchangeCode troca o code recebido na RedirectUri pelas credenciais, sem alterar as credenciais do client
+// (que pode estar atendendo vários usuários, ver CallbackHandler). Para utilizá-las neste client, ver SetCredentials
+func (c *Client) ExchangeCode(ctx context.Context, code string) (Credentials, error) {
+	if !c.initialized {
+		return Credentials{}, ErrClientNotInitialized
+	}
+
+	credentials := c.Credentials()
+	aResp, err := c.requestToken(ctx, &authRequest{
 		GrantType:    "authorization_code",
-		ClientId:     c.config.Credentials.ClientId,
-		ClientSecret: c.config.Credentials.ClientSecret,
+		ClientId:     credentials.ClientId,
+		ClientSecret: credentials.ClientSecret,
 		RedirectUri:  c.config.RedirectUri,
-		Code:         c.config.Credentials.Code,
-	}
-	err := json.NewEncoder(buf).Encode(aReq)
+		Code:         code,
+	})
 	if err != nil {
-		return err
+		return Credentials{}, err
 	}
 
-	req, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/oauth/token", buf)
-	if err != nil {
-		return err
+	exchanged := Credentials{
+		ClientId:     credentials.ClientId,
+		ClientSecret: credentials.ClientSecret,
+		AccessToken:  aResp.AccessToken,
+		RefreshToken: aResp.RefreshToken,
+		ExpiresAt:    aResp.expiresAt(),
 	}
 
-	c.injectDefaultHeaders(req)
+	// identifica a conta dona das credenciais, para que seja gravada junto com elas
+	// a consulta é opcional: o code já foi consumido, então uma falha aqui (ex: sem o escopo users-read)
+	// apenas deixa User nil, em vez de descartar as credenciais e obrigar uma nova autorização
+	if c.config.FetchUserOnAuthenticate {
+		user, err := c.fetchUser(ctx, exchanged.AccessToken)
+		if err == nil {
+			exchanged.User = user
+		}
+	}
 
-	response, err := c.httpClient.Do(req)
+	return exchanged, nil
+}
+
+// SetCredentials substitui os tokens do client (ex: pelos obtidos via ExchangeCode),
+// salvando no TokenStore e chamando o CredentialsChangedCallback
+func (c *Client) SetCredentials(ctx context.Context, credentials Credentials) error {
+	if !c.initialized {
+		return ErrClientNotInitialized
+	}
+
+	unlock, err := c.lockStore(ctx)
 	if err != nil {
 		return err
 	}
-	defer response.Body.Close()
+	defer unlock()
 
-	return c.parseAuthResponse(response)
+	return c.applyCredentials(ctx, credentials, "")
 }
 
 func (c *Client) RefreshToken() error {
+	return c.RefreshTokenContext(c.context)
+}
+
+func (c *Client) RefreshTokenContext(ctx context.Context) error {
 	if !c.initialized {
 		return ErrClientNotInitialized
 	}
 
-	if c.config.Credentials.RefreshToken == "" {
-		return ErrInvalidToken
+	_, err := c.refresh(ctx, c.Credentials().AccessToken)
+	return err
+}
+
+// executa o refresh propriamente dito, deve ser chamado apenas via singleFlight
+func (c *Client) refreshToken(ctx context.Context) error {
+	unlock, err := c.lockStore(ctx)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	// outro processo pode ter atualizado o token, e com isso invalidado o refresh token que temos em memória
+	updated, err := c.reloadFromStore(ctx)
+	if err != nil && !errors.Is(err, ErrCredentialsNotFound) {
+		return fmt.Errorf("melhor envio: token store: %w", err)
+	}
+	if updated {
+		return nil
+	}
 
-	buf := &bytes.Buffer{}
-	aReq := &authRequest{
-		GrantType:    "refresh_token",
-		ClientId:     c.config.Credentials.ClientId,
-		ClientSecret: c.config.Credentials.ClientSecret,
-		RefreshToken: c.config.Credentials.RefreshToken,
+	credentials := c.Credentials()
+	if credentials.RefreshToken == "" {
+		return ErrInvalidToken
 	}
-	err := json.NewEncoder(buf).Encode(aReq)
+
+	aResp, err := c.requestToken(ctx, &authRequest{
+		GrantType:    "refresh_token",
+		ClientId:     credentials.ClientId,
+		ClientSecret: credentials.ClientSecret,
+		RefreshToken: credentials.RefreshToken,
+	})
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/oauth/token", buf)
+	credentials.AccessToken = aResp.AccessToken
+	credentials.RefreshToken = aResp.RefreshToken
+	credentials.ExpiresAt = aResp.expiresAt()
+	credentials.User = nil
+	return c.applyCredentials(ctx, credentials, "")
+}
+
+func (c *Client) requestToken(ctx context.Context, aReq *authRequest) (*authResponse, error) {
+	req, err := c.newRequest(ctx, "POST", "/oauth/token", aReq)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	c.injectDefaultHeaders(req)
 
 	response, err := c.httpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer response.Body.Close()
 
-	return c.parseAuthResponse(response)
+	return parseAuthResponse(response)
 }
 
-func (c *Client) parseAuthResponse(response *http.Response) error {
+func parseAuthResponse(response *http.Response) (*authResponse, error) {
 	body, _ := io.ReadAll(response.Body)
 
 	switch response.StatusCode {
@@ -120,25 +195,35 @@ func (c *Client) parseAuthResponse(response *http.Response) error {
 		aResp := &authResponse{}
 		err := json.Unmarshal(body, aResp)
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		c.config.Credentials.AccessToken = aResp.AccessToken
-		c.config.Credentials.RefreshToken = aResp.RefreshToken
-		c.config.Credentials.ExpiresAt = time.Now().Add(time.Duration(aResp.ExpiresIn) * time.Second)
-		c.config.Credentials.Code = ""
-
-		if c.config.CredentialsChangedCallback != nil {
-			err = c.config.CredentialsChangedCallback(c.config.Credentials)
-			if err != nil {
-				return err
-			}
-		}
-
-		return nil
+		return aResp, nil
 	case http.StatusUnauthorized:
-		return ErrInvalidToken
+		return nil, ErrInvalidToken
 	default:
-		return fmt.Errorf("melhor envio: auth: unrecognized response: %v %v", response.StatusCode, string(body))
+		return nil, parseAPIError(response, body)
+	}
+}
+
+func (aResp *authResponse) expiresAt() time.Time {
+	return time.Now().Add(time.Duration(aResp.ExpiresIn) * time.Second)
+}
+
+// aplica os tokens de credentials ao client (User apenas quando informado) e notifica a alteração
+// o Code do client só é limpo quando for o code que acabou de ser trocado, deve ser chamado com o store travado
+func (c *Client) applyCredentials(ctx context.Context, credentials Credentials, exchangedCode string) error {
+	c.mutex.Lock()
+	if credentials.User != nil {
+		c.config.Credentials.User = credentials.User
+	}
+	c.config.Credentials.AccessToken = credentials.AccessToken
+	c.config.Credentials.RefreshToken = credentials.RefreshToken
+	c.config.Credentials.ExpiresAt = credentials.ExpiresAt
+	if exchangedCode != "" && c.config.Credentials.Code == exchangedCode {
+		c.config.Credentials.Code = ""
 	}
+	current := c.config.Credentials
+	c.mutex.Unlock()
+
+	return c.credentialsChanged(ctx, current, true)
 }
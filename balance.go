@@ -0,0 +1,149 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+)
+
+type Gateway string
+
+const (
+	Gateway_MercadoPago Gateway = "mercado-pago"
+	Gateway_PagSeguro   Gateway = "pagseguro"
+	Gateway_PicPay      Gateway = "picpay"
+	Gateway_Pix         Gateway = "pix"
+)
+
+type Balance struct {
+	Balance  float64 `json:"balance"`
+	Reserved float64 `json:"reserved"`
+	Debts    float64 `json:"debts"`
+}
+
+type AddBalanceRequest struct {
+	Gateway Gateway `json:"gateway"`
+	Value   float64 `json:"value"`
+	// url para onde o usuário volta após o pagamento
+	Redirect string `json:"redirect,omitempty"`
+}
+
+// dados para o pagamento da recarga, o usuário deve ser redirecionado para Redirect
+// (ou pagar via Digitable/QrCode, dependendo do gateway)
+type AddBalanceResponse struct {
+	Id        string `json:"id"`
+	Redirect  string `json:"redirect"`
+	Digitable string `json:"digitable,omitempty"`
+	QrCode    string `json:"qrcode,omitempty"`
+}
+
+type BalanceError struct {
+	APIError
+}
+
+func (be *BalanceError) Error() string {
+	return "melhor envio: balance: " + be.describe()
+}
+
+func (be *BalanceError) Unwrap() error {
+	return &be.APIError
+}
+
+func (c *Client) Balance(ctx context.Context) (*Balance, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me/balance", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp *Balance
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: balance: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &BalanceError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
+
+// AddBalance solicita uma recarga da carteira, retornando os dados para pagamento
+func (c *Client) AddBalance(ctx context.Context, req *AddBalanceRequest) (*AddBalanceResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/balance", req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *AddBalanceResponse
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: balance: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &BalanceError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
+
+type BalanceCheck struct {
+	Balance float64
+	// soma do Price das etiquetas informadas
+	Total      float64
+	Sufficient bool
+	// valor que falta para cobrir o total (zero quando suficiente)
+	Missing float64
+}
+
+// CheckBalance verifica, antes do Checkout, se o saldo da carteira cobre as etiquetas informadas
+func (c *Client) CheckBalance(ctx context.Context, orderIds []string) (*BalanceCheck, error) {
+	balance, err := c.Balance(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &BalanceCheck{Balance: balance.Balance}
+	for _, orderId := range orderIds {
+		order, err := c.GetOrder(ctx, orderId)
+		if err != nil {
+			return nil, err
+		}
+		check.Total += order.Price
+	}
+
+	// os valores são em reais, arredonda para centavos para evitar erros de ponto flutuante na comparação
+	check.Total = math.Round(check.Total*100) / 100
+	check.Sufficient = check.Balance >= check.Total
+	if !check.Sufficient {
+		check.Missing = math.Round((check.Total-check.Balance)*100) / 100
+	}
+
+	return check, nil
+}
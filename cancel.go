@@ -0,0 +1,164 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type CancelReason string
+
+const (
+	// único motivo aceito atualmente pela api para cancelamento solicitado pelo próprio cliente
+	// (o titular da conta), o detalhamento do motivo vai em description
+	CancelReason_Customer CancelReason = "2"
+)
+
+type Cancellable struct {
+	Cancellable bool `json:"cancellable"`
+}
+
+type cancelOrder struct {
+	Id          string       `json:"id"`
+	ReasonId    CancelReason `json:"reason_id"`
+	Description string       `json:"description"`
+}
+
+type cancelRequest struct {
+	Order cancelOrder `json:"order"`
+}
+
+type CancelResponse struct {
+	Canceled bool `json:"canceled"`
+
+	// estorno da etiqueta, nil quando ela ainda não havia sido paga ou quando a consulta do pedido falhou
+	// a api não o inclui na resposta do cancelamento, é preenchido por Cancel a partir do pedido
+	Refund *CancelRefund `json:"-"`
+}
+
+// o valor de uma etiqueta cancelada após o pagamento é estornado para o saldo da carteira
+type CancelRefund struct {
+	OrderId string
+	// valor pago pela etiqueta (já com o desconto), que volta para a carteira
+	Value      float64
+	PaidAt     time.Time
+	CanceledAt time.Time
+}
+
+type CancelError struct {
+	APIError
+}
+
+func (ce *CancelError) Error() string {
+	return "melhor envio: cancel: " + ce.describe()
+}
+
+func (ce *CancelError) Unwrap() error {
+	return &ce.APIError
+}
+
+// Cancellable informa, para cada etiqueta, se ela ainda pode ser cancelada
+func (c *Client) Cancellable(ctx context.Context, orderIds []string) (map[string]*Cancellable, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/cancellable", &ordersRequest{Orders: orderIds})
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp map[string]*Cancellable
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: cancel: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &CancelError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
+
+// Cancel cancela uma etiqueta já paga ou gerada, para itens ainda no carrinho use RemoveFromCart
+// quando a etiqueta havia sido paga, o Refund da resposta traz o valor estornado para a carteira
+// a consulta do estorno é feita depois do cancelamento e é apenas informativa, caso ela falhe o
+// cancelamento continua sendo retornado com sucesso, com Refund nil
+func (c *Client) Cancel(ctx context.Context, orderId string, reason CancelReason, description string) (*CancelResponse, error) {
+	resp, err := c.cancel(ctx, orderId, reason, description)
+	if err != nil || !resp.Canceled {
+		return resp, err
+	}
+
+	order, err := c.GetOrder(ctx, orderId)
+	if err == nil {
+		resp.Refund, _ = refundFromOrder(order)
+	}
+
+	return resp, nil
+}
+
+func refundFromOrder(order *CartResponse) (*CancelRefund, error) {
+	paidAt, err := parseApiTime(&order.PaidAt)
+	if err != nil || paidAt.IsZero() {
+		return nil, err
+	}
+	canceledAt, err := parseApiTime(&order.CanceledAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CancelRefund{
+		OrderId:    order.Id,
+		Value:      order.Price,
+		PaidAt:     paidAt,
+		CanceledAt: canceledAt,
+	}, nil
+}
+
+func (c *Client) cancel(ctx context.Context, orderId string, reason CancelReason, description string) (*CancelResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/cancel", &cancelRequest{
+		Order: cancelOrder{
+			Id:          orderId,
+			ReasonId:    reason,
+			Description: description,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp map[string]*CancelResponse
+		err = json.Unmarshal(body, &resp)
+		if err != nil || resp[orderId] == nil {
+			return nil, fmt.Errorf("melhor envio: cancel: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp[orderId], nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &CancelError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
@@ -0,0 +1,46 @@
+package melhorenvio
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestCancelReportsRefund(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/me/shipment/cancel":
+			w.Write([]byte(`{"order-1":{"canceled":true}}`))
+		case "/api/v2/me/orders/order-1":
+			w.Write([]byte(`{"id":"order-1","price":23.5,"paid_at":"2024-03-01 10:00:00","canceled_at":"2024-03-01 11:00:00"}`))
+		}
+	})
+	c := newTestClient(srv, Config{})
+
+	resp, err := c.Cancel(context.Background(), "order-1", CancelReason_Customer, "erro no pedido")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Canceled || resp.Refund == nil || resp.Refund.Value != 23.5 || resp.Refund.PaidAt.IsZero() {
+		t.Fatalf("unexpected response: %+v %+v", resp, resp.Refund)
+	}
+}
+
+func TestCancelSucceedsWhenRefundLookupFails(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/me/shipment/cancel" {
+			w.Write([]byte(`{"order-1":{"canceled":true}}`))
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c := newTestClient(srv, Config{})
+
+	resp, err := c.Cancel(context.Background(), "order-1", CancelReason_Customer, "erro no pedido")
+	if err != nil {
+		t.Fatalf("successful cancellation reported as failure: %v", err)
+	}
+	if !resp.Canceled || resp.Refund != nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
@@ -1,13 +1,17 @@
 package melhorenvio
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
 )
 
+const defaultCartConcurrency = 4
+
 type CartToFrom struct {
 	Name            string `json:"name,omitempty"`
 	Phone           string `json:"phone,omitempty"`
@@ -37,6 +41,10 @@ type CartProduct struct {
 type CartVolume struct {
 	Dimensions
 	Weight float64 `json:"weight"`
+
+	// usados apenas na validação local (Validate), ver Volume
+	Format   PackageFormat `json:"-"`
+	Diameter float64       `json:"-"`
 }
 
 type CartOptions struct {
@@ -54,6 +62,9 @@ type CartOptions struct {
 }
 
 type AddToCartRequest struct {
+	// quando informado, os campos vazios de From são preenchidos a partir da loja (ver CartFromStore)
+	FromStoreId string `json:"-"`
+
 	Service  int32         `json:"service"`
 	Agency   int32         `json:"agency,omitempty"`
 	From     CartToFrom    `json:"from"`
@@ -130,22 +141,39 @@ type CartResponse struct {
 }
 
 type CartError struct {
-	Message string              `json:"message"`
-	Errors  map[string][]string `json:"error"`
+	APIError
 }
 
 func (ce *CartError) Error() string {
-	return "melhor envio: cart: " + ce.Message + ": " + fmt.Sprintf("%v", ce.Errors)
+	return "melhor envio: cart: " + ce.describe()
+}
+
+func (ce *CartError) Unwrap() error {
+	return &ce.APIError
+}
+
+func newCartError(e *APIError) error {
+	return &CartError{APIError: *e}
 }
 
 func (c *Client) AddToCart(req *AddToCartRequest) (*CartResponse, error) {
-	buf := &bytes.Buffer{}
-	err := json.NewEncoder(buf).Encode(req)
-	if err != nil {
-		return nil, err
+	return c.AddToCartContext(c.context, req)
+}
+
+func (c *Client) AddToCartContext(ctx context.Context, req *AddToCartRequest) (*CartResponse, error) {
+	if req.FromStoreId != "" {
+		from, err := c.CartFromStore(ctx, req.FromStoreId)
+		if err != nil {
+			return nil, err
+		}
+
+		// cópia para não alterar o request de quem chamou
+		filled := *req
+		filled.From = mergeCartToFrom(req.From, from)
+		req = &filled
 	}
 
-	httpReq, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/api/v2/me/cart", buf)
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/cart", req)
 	if err != nil {
 		return nil, err
 	}
@@ -167,23 +195,19 @@ func (c *Client) AddToCart(req *AddToCartRequest) (*CartResponse, error) {
 		}
 
 		return resp, nil
-	case http.StatusUnprocessableEntity:
-		ret := &CartError{}
-		err = json.Unmarshal(body, ret)
-		if err != nil {
-			return nil, fmt.Errorf("melhor envio: cart: unrecognized response: %v %v", httpResp.StatusCode, string(body))
-		}
-		return nil, ret
-
 	case http.StatusUnauthorized:
 		return nil, ErrInvalidToken
 	default:
-		return nil, fmt.Errorf("melhor envio: cart: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return nil, newCartError(parseAPIError(httpResp, body))
 	}
 }
 
 func (c *Client) RemoveFromCart(orderId string) error {
-	httpReq, err := http.NewRequestWithContext(c.context, "DELETE", c.config.ApiUrl+"/api/v2/me/cart/"+orderId, nil)
+	return c.RemoveFromCartContext(c.context, orderId)
+}
+
+func (c *Client) RemoveFromCartContext(ctx context.Context, orderId string) error {
+	httpReq, err := c.newRequest(ctx, "DELETE", "/api/v2/me/cart/"+url.PathEscape(orderId), nil)
 	if err != nil {
 		return err
 	}
@@ -199,17 +223,110 @@ func (c *Client) RemoveFromCart(orderId string) error {
 	switch httpResp.StatusCode {
 	case http.StatusNoContent:
 		return nil
-	case http.StatusUnprocessableEntity, http.StatusBadRequest:
-		ret := &CartError{}
-		err = json.Unmarshal(body, ret)
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	default:
+		return newCartError(parseAPIError(httpResp, body))
+	}
+}
+
+// ListCart retorna todos os itens do carrinho, percorrendo todas as páginas
+func (c *Client) ListCart(ctx context.Context) ([]*CartResponse, error) {
+	var items []*CartResponse
+
+	it := c.IterateCart(ctx)
+	for it.Next() {
+		items = append(items, it.Value())
+	}
+	return items, it.Err()
+}
+
+func (c *Client) IterateCart(ctx context.Context) *Iterator[*CartResponse] {
+	return newIterator(ctx, "/api/v2/me/cart", func(ctx context.Context, path string) (*Page[*CartResponse], error) {
+		return fetchPage[*CartResponse](ctx, c, path, newCartError)
+	})
+}
+
+func (c *Client) GetCartItem(ctx context.Context, orderId string) (*CartResponse, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me/cart/"+url.PathEscape(orderId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp *CartResponse
+		err = json.Unmarshal(body, &resp)
 		if err != nil {
-			return fmt.Errorf("melhor envio: cart: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+			return nil, fmt.Errorf("melhor envio: cart: unrecognized response: %v %v", httpResp.StatusCode, string(body))
 		}
-		return ret
 
+		return resp, nil
 	case http.StatusUnauthorized:
-		return ErrInvalidToken
+		return nil, ErrInvalidToken
 	default:
-		return fmt.Errorf("melhor envio: cart: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return nil, newCartError(parseAPIError(httpResp, body))
+	}
+}
+
+type RemoveFromCartResult struct {
+	OrderId string
+	Err     error
+}
+
+// RemoveFromCartMany remove os itens em paralelo, com no máximo concurrency remoções simultâneas
+// (padrão de 4 quando zero). O resultado segue a mesma ordem de orderIds
+func (c *Client) RemoveFromCartMany(ctx context.Context, orderIds []string, concurrency int) []RemoveFromCartResult {
+	if concurrency <= 0 {
+		concurrency = defaultCartConcurrency
+	}
+
+	results := make([]RemoveFromCartResult, len(orderIds))
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, orderId := range orderIds {
+		results[i].OrderId = orderId
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, orderId string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i].Err = c.RemoveFromCartContext(ctx, orderId)
+		}(i, orderId)
 	}
+	wg.Wait()
+
+	return results
+}
+
+// ClearCart remove todos os itens do carrinho, útil para limpar itens deixados por processos interrompidos
+func (c *Client) ClearCart(ctx context.Context, concurrency int) ([]RemoveFromCartResult, error) {
+	items, err := c.ListCart(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	orderIds := make([]string, len(items))
+	for i, item := range items {
+		orderIds[i] = item.Id
+	}
+
+	return c.RemoveFromCartMany(ctx, orderIds, concurrency), nil
 }
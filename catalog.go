@@ -0,0 +1,120 @@
+package melhorenvio
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultCatalogTTL = time.Hour
+
+type catalogEntry struct {
+	value     interface{}
+	etag      string
+	fetchedAt time.Time
+}
+
+// Catalog mantém em memória os serviços e transportadoras, que mudam raramente.
+// Após o ttl a api é consultada novamente, com revalidação via ETag quando disponível.
+// Os valores retornados são compartilhados entre as chamadas e não devem ser modificados.
+type Catalog struct {
+	client *Client
+	ttl    time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*catalogEntry
+}
+
+func NewCatalog(client *Client, ttl time.Duration) *Catalog {
+	if ttl <= 0 {
+		ttl = defaultCatalogTTL
+	}
+
+	return &Catalog{
+		client:  client,
+		ttl:     ttl,
+		entries: map[string]*catalogEntry{},
+	}
+}
+
+func (cat *Catalog) Services(ctx context.Context) ([]*Service, error) {
+	return catalogGet[[]*Service](ctx, cat, "/api/v2/me/shipment/services")
+}
+
+func (cat *Catalog) Service(ctx context.Context, serviceId int32) (*Service, error) {
+	services, err := cat.Services(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, service := range services {
+		if service.ID == serviceId {
+			return service, nil
+		}
+	}
+
+	// serviço novo, ainda não presente na listagem em cache
+	return catalogGet[*Service](ctx, cat, "/api/v2/me/shipment/services/"+strconv.FormatInt(int64(serviceId), 10))
+}
+
+func (cat *Catalog) Companies(ctx context.Context) ([]*Company, error) {
+	return catalogGet[[]*Company](ctx, cat, "/api/v2/me/shipment/companies")
+}
+
+func (cat *Catalog) Company(ctx context.Context, companyId int32) (*Company, error) {
+	companies, err := cat.Companies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, company := range companies {
+		if company.ID == companyId {
+			return company, nil
+		}
+	}
+
+	return catalogGet[*Company](ctx, cat, "/api/v2/me/shipment/companies/"+strconv.FormatInt(int64(companyId), 10))
+}
+
+// Invalidate descarta todo o cache
+func (cat *Catalog) Invalidate() {
+	cat.mutex.Lock()
+	defer cat.mutex.Unlock()
+
+	cat.entries = map[string]*catalogEntry{}
+}
+
+func catalogGet[T any](ctx context.Context, cat *Catalog, path string) (T, error) {
+	cat.mutex.Lock()
+	entry := cat.entries[path]
+	cat.mutex.Unlock()
+
+	if entry != nil && time.Since(entry.fetchedAt) < cat.ttl {
+		return entry.value.(T), nil
+	}
+
+	etag := ""
+	if entry != nil {
+		etag = entry.etag
+	}
+
+	value, newEtag, notModified, err := getPublic[T](ctx, cat.client, path, etag)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if notModified {
+		value = entry.value.(T)
+	}
+
+	cat.mutex.Lock()
+	cat.entries[path] = &catalogEntry{
+		value:     value,
+		etag:      newEtag,
+		fetchedAt: time.Now(),
+	}
+	cat.mutex.Unlock()
+
+	return value, nil
+}
@@ -0,0 +1,112 @@
+package melhorenvio
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCatalog(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.URL.Path+" "+r.Header.Get("If-None-Match"))
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/api/v2/me/shipment/services":
+			if r.Header.Get("If-None-Match") == `"services-1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"services-1"`)
+			w.Write([]byte(`[{"id":1,"name":"PAC"},{"id":2,"name":"SEDEX"}]`))
+		case "/api/v2/me/shipment/services/3":
+			w.Write([]byte(`{"id":3,"name":"Mini Envios"}`))
+		case "/api/v2/me/shipment/companies":
+			w.Write([]byte(`[{"id":1,"name":"Correios"}]`))
+		case "/api/v2/me/shipment/companies/5":
+			w.Write([]byte(`{"id":5,"name":"Nova"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	c := newTestClient(srv, Config{})
+	cat := NewCatalog(c, time.Hour)
+	ctx := context.Background()
+
+	expectRequests := func(expected ...string) {
+		t.Helper()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		if len(requests) != len(expected) {
+			t.Fatalf("expected requests %q, got %q", expected, requests)
+		}
+		for i := range expected {
+			if requests[i] != expected[i] {
+				t.Fatalf("expected requests %q, got %q", expected, requests)
+			}
+		}
+		requests = nil
+	}
+
+	services, err := cat.Services(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cat.Services(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectRequests("/api/v2/me/shipment/services ")
+
+	// após o ttl, a listagem é revalidada e o 304 reaproveita o valor em cache
+	cat.entries["/api/v2/me/shipment/services"].fetchedAt = time.Now().Add(-2 * time.Hour)
+	revalidated, err := cat.Services(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revalidated) != 2 || revalidated[0] != services[0] {
+		t.Fatalf("cached services not reused after 304: %+v", revalidated)
+	}
+	if _, err := cat.Services(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectRequests(`/api/v2/me/shipment/services "services-1"`)
+
+	// serviço presente na listagem, sem consulta
+	service, err := cat.Service(ctx, 2)
+	if err != nil || service.Name != "SEDEX" {
+		t.Fatalf("unexpected service: %+v %v", service, err)
+	}
+	expectRequests()
+
+	// serviço ausente da listagem em cache, consultado pelo id
+	for i := 0; i < 2; i++ {
+		service, err = cat.Service(ctx, 3)
+		if err != nil || service.Name != "Mini Envios" {
+			t.Fatalf("unexpected service: %+v %v", service, err)
+		}
+	}
+	expectRequests("/api/v2/me/shipment/services/3 ")
+
+	company, err := cat.Company(ctx, 1)
+	if err != nil || company.Name != "Correios" {
+		t.Fatalf("unexpected company: %+v %v", company, err)
+	}
+	company, err = cat.Company(ctx, 5)
+	if err != nil || company.Name != "Nova" {
+		t.Fatalf("unexpected company: %+v %v", company, err)
+	}
+	expectRequests("/api/v2/me/shipment/companies ", "/api/v2/me/shipment/companies/5 ")
+
+	// sem cache, a consulta não envia o ETag anterior
+	cat.Invalidate()
+	if _, err := cat.Services(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expectRequests("/api/v2/me/shipment/services ")
+}
@@ -1,7 +1,7 @@
 package melhorenvio
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -30,21 +30,23 @@ type CheckoutResponse struct {
 }
 
 type CheckoutError struct {
-	Errors string `json:"error"`
+	APIError
 }
 
 func (ce *CheckoutError) Error() string {
-	return "melhor envio: checkout: " + ce.Errors
+	return "melhor envio: checkout: " + ce.describe()
+}
+
+func (ce *CheckoutError) Unwrap() error {
+	return &ce.APIError
 }
 
 func (c *Client) Checkout(req *CheckoutRequest) (*CheckoutResponse, error) {
-	buf := &bytes.Buffer{}
-	err := json.NewEncoder(buf).Encode(req)
-	if err != nil {
-		return nil, err
-	}
+	return c.CheckoutContext(c.context, req)
+}
 
-	httpReq, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/api/v2/me/shipment/checkout", buf)
+func (c *Client) CheckoutContext(ctx context.Context, req *CheckoutRequest) (*CheckoutResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/checkout", req)
 	if err != nil {
 		return nil, err
 	}
@@ -69,17 +71,9 @@ func (c *Client) Checkout(req *CheckoutRequest) (*CheckoutResponse, error) {
 		}
 
 		return resp, nil
-	case http.StatusUnprocessableEntity:
-		ret := &CheckoutError{}
-		err = json.Unmarshal(body, ret)
-		if err != nil {
-			return nil, fmt.Errorf("melhor envio: checkout: unrecognized response: %v %v", httpResp.StatusCode, string(body))
-		}
-		return nil, ret
-
 	case http.StatusUnauthorized:
 		return nil, ErrInvalidToken
 	default:
-		return nil, fmt.Errorf("melhor envio: checkout: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return nil, &CheckoutError{APIError: *parseAPIError(httpResp, body)}
 	}
 }
@@ -1,10 +1,14 @@
 package melhorenvio
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -13,19 +17,25 @@ type Environment string
 const (
 	SandboxApiUrl    = "https://sandbox.melhorenvio.com.br"
 	ProductionApiUrl = "https://melhorenvio.com.br"
+
+	defaultRetryBackoff = 500 * time.Millisecond
+	defaultRefreshSkew  = 30 * time.Second
 )
 
 type CredentialsChangedCallback = func(credentials Credentials) error
 
 type Credentials struct {
-	ClientId     int32
-	ClientSecret string
+	ClientId     int32  `json:"client_id,omitempty"`
+	ClientSecret string `json:"-"`
+
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
 
-	AccessToken  string
-	RefreshToken string
-	ExpiresAt    time.Time
+	Code string `json:"code,omitempty"`
 
-	Code string
+	// conta dona das credenciais, preenchida quando Config.FetchUserOnAuthenticate está ativo
+	User *User `json:"user,omitempty"`
 }
 
 type Config struct {
@@ -37,7 +47,27 @@ type Config struct {
 	ApplicationName string
 	Email           string
 
+	// http.Client utilizado nas requisições (padrão http.DefaultClient)
+	HttpClient *http.Client
+
+	// quantidade máxima de reenvios em caso de falha transitória (429, 502, 503 e 504)
+	// requisições não idempotentes (POST, PATCH) só são reenviadas em 429 e 503, em que a api
+	// não chegou a processar a requisição, já que um 502/504 pode chegar depois do processamento
+	// e o reenvio duplicaria o pedido no carrinho, o checkout, etc
+	MaxRetries int
+
+	// antecedência com que o token é atualizado antes de ExpiresAt (padrão de 30 segundos, negativo desativa)
+	RefreshSkew time.Duration
+
 	CredentialsChangedCallback CredentialsChangedCallback
+
+	// busca o perfil do usuário (Me) logo após a troca do code, preenchendo Credentials.User
+	// (que fica nil caso a consulta falhe, sem impedir a autenticação)
+	FetchUserOnAuthenticate bool
+
+	// quando informado, as credenciais são carregadas do store na primeira utilização do client
+	// e salvas nele a cada atualização de token
+	TokenStore TokenStore
 }
 
 type Client struct {
@@ -48,7 +78,15 @@ type Client struct {
 	httpClient  *http.Client
 	initialized bool
 
-	mutex sync.Mutex
+	// protege config.Credentials, flights e storeLoaded
+	mutex       sync.Mutex
+	flights     map[string]*refreshCall
+	storeLoaded bool
+
+	// requisições e atualizações de credenciais em andamento, ver Manager
+	inflight atomic.Int32
+
+	senders senderCache
 }
 
 func NewClient(ctx context.Context, config Config) *Client {
@@ -58,7 +96,10 @@ func NewClient(ctx context.Context, config Config) *Client {
 	if c.config.ApiUrl == "" {
 		c.config.ApiUrl = SandboxApiUrl
 	}
-	c.httpClient = http.DefaultClient
+	c.httpClient = c.config.HttpClient
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
 	c.initialized = true
 
 	return c
@@ -69,54 +110,172 @@ func (c *Client) injectDefaultHeaders(req *http.Request) {
 		return
 	}
 
-	req.Header.Set("Accept", "application/json")
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", c.config.ApplicationName+" ("+c.config.Email+")")
 }
 
-func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
-	// faz a requisição, já injetando a autenticação e gerenciando o processo de refresh de token
-	// ao dar retry por conta da autenticação, pode dar erro se o body do request não for um dos tipos
-	// que é possível fazer o retry (ex: bytes.Buffer)
-	c.injectDefaultHeaders(req)
-
-	if c.config.Credentials.ExpiresAt.Before(time.Now()) {
-		err := c.RefreshToken()
+// monta um request da api com o body já serializado em memória, de forma que
+// ele possa ser reenviado quantas vezes forem necessárias (ver GetBody)
+func (c *Client) newRequest(ctx context.Context, method string, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
 		if err != nil {
 			return nil, err
 		}
+		reader = bytes.NewReader(payload)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.config.Credentials.AccessToken)
+	return http.NewRequestWithContext(ctx, method, c.config.ApiUrl+path, reader)
+}
+
+// garante que o body do request possa ser lido novamente, bufferizando em memória
+// os bodies que não foram criados com GetBody
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
 
-	response, err := c.httpClient.Do(req)
+	payload, err := io.ReadAll(req.Body)
+	req.Body.Close()
 	if err != nil {
-		return response, err
+		return err
 	}
 
-	switch response.StatusCode {
-	case http.StatusUnauthorized:
-		io.Copy(io.Discard, response.Body)
-		response.Body.Close()
+	req.ContentLength = int64(len(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
 
-		err = c.RefreshToken()
+// cria uma cópia do request com o body rebobinado, para ser reenviada
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
 		if err != nil {
 			return nil, err
 		}
+		retry.Body = body
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return nil, ErrRequestNotReplayable
+	}
+	return retry, nil
+}
 
-		req.Header.Set("Authorization", "Bearer "+c.config.Credentials.AccessToken)
+func isTransientStatus(method string, statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return isIdempotent(method)
+	}
+	return false
+}
 
-		response, err = c.httpClient.Do(req)
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryDelay(response *http.Response, retry int) time.Duration {
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultRetryBackoff << retry
+}
+
+func discardBody(response *http.Response) {
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+}
+
+func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
+	return c.do(req, true)
+}
+
+// utilizado nas rotas públicas da api, não envia os dados de autenticação
+func (c *Client) doPublicRequest(req *http.Request) (*http.Response, error) {
+	return c.do(req, false)
+}
+
+func (c *Client) do(req *http.Request, authenticated bool) (*http.Response, error) {
+	// faz a requisição, já injetando a autenticação e gerenciando o processo de refresh de token
+	// todo reenvio (refresh de token ou falha transitória) é feito a partir de uma cópia do request
+	// com o body rebobinado, garantindo que o mesmo payload seja enviado em todas as tentativas
+	c.inflight.Add(1)
+	defer c.inflight.Add(-1)
+
+	c.injectDefaultHeaders(req)
+
+	err := bufferBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshed := false
+	retries := 0
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			req, err = rewindRequest(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		token := ""
+		if authenticated {
+			token, err = c.accessToken(req.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		response, err := c.httpClient.Do(req)
 		if err != nil {
 			return response, err
 		}
-		// don't close body
 
-		// probably not needed, but I'll check anyway
-		switch response.StatusCode {
-		case http.StatusUnauthorized:
-			return nil, ErrInvalidToken
+		switch {
+		case response.StatusCode == http.StatusUnauthorized && authenticated:
+			discardBody(response)
+			if refreshed {
+				return nil, ErrInvalidToken
+			}
+
+			_, err = c.refresh(req.Context(), token)
+			if err != nil {
+				return nil, err
+			}
+			refreshed = true
+			continue
+
+		case isTransientStatus(req.Method, response.StatusCode) && retries < c.config.MaxRetries:
+			delay := retryDelay(response, retries)
+			discardBody(response)
+			retries++
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+			continue
 		}
+
+		// don't close body
+		return response, nil
 	}
-	return response, err
 }
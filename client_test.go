@@ -0,0 +1,196 @@
+package melhorenvio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// servidor fake que responde /oauth/token e delega as demais rotas para handler
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+
+	var mutex sync.Mutex
+	refreshes := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			mutex.Lock()
+			refreshes++
+			n := refreshes
+			mutex.Unlock()
+			fmt.Fprintf(w, `{"access_token":"token-%d","refresh_token":"refresh-%d","expires_in":3600}`, n, n)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestClient(srv *httptest.Server, config Config) *Client {
+	config.ApiUrl = srv.URL
+	if config.Credentials.AccessToken == "" {
+		config.Credentials.AccessToken = "token-0"
+		config.Credentials.RefreshToken = "refresh-0"
+		config.Credentials.ExpiresAt = time.Now().Add(time.Hour)
+	}
+	return NewClient(context.Background(), config)
+}
+
+func TestDoRequestReplaysBodyAfterRefresh(t *testing.T) {
+	var bodies []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if r.Header.Get("Authorization") == "Bearer token-0" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"order-1"}`))
+	})
+	c := newTestClient(srv, Config{})
+
+	resp, err := c.AddToCart(&AddToCartRequest{Service: 1, From: CartToFrom{Name: "Loja"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Id != "order-1" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(bodies))
+	}
+	if bodies[0] == "" || bodies[0] != bodies[1] {
+		t.Fatalf("retry sent a different body:\n%q\n%q", bodies[0], bodies[1])
+	}
+}
+
+func TestDoRequestReplaysBodyAfterTransientFailure(t *testing.T) {
+	var bodies []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if len(bodies) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	c := newTestClient(srv, Config{MaxRetries: 2})
+
+	_, err := c.CotarFrete(&CotacaoRequest{From: ToFrom{PostalCode: "01001000"}, To: ToFrom{PostalCode: "20040002"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(bodies))
+	}
+	for _, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Fatalf("retry sent a different body:\n%q\n%q", bodies[0], body)
+		}
+	}
+}
+
+func TestDoRequestStopsAfterMaxRetries(t *testing.T) {
+	attempts := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c := newTestClient(srv, Config{MaxRetries: 1})
+
+	_, err := c.CotarFrete(&CotacaoRequest{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestDoRequestBuffersUnreplayableBody(t *testing.T) {
+	var bodies []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	c := newTestClient(srv, Config{})
+
+	// io.MultiReader não é um dos tipos para os quais o http.NewRequest preenche o GetBody
+	req, err := http.NewRequest("POST", srv.URL+"/api/v2/me/cart", io.MultiReader(strings.NewReader(`{"a":1}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if len(bodies) != 2 || bodies[0] != `{"a":1}` || bodies[1] != bodies[0] {
+		t.Fatalf("unexpected bodies: %q", bodies)
+	}
+}
+
+func TestDoRequestDoesNotResendPostOnGatewayErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusGatewayTimeout} {
+		attempts := 0
+		srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+		})
+		c := newTestClient(srv, Config{MaxRetries: 3})
+
+		// o upstream pode ter processado o pedido antes do 502/504, reenviar duplicaria o item no carrinho
+		_, err := c.AddToCart(&AddToCartRequest{Service: 1})
+		if err == nil {
+			t.Fatalf("%d: expected error", status)
+		}
+		if attempts != 1 {
+			t.Fatalf("%d: POST sent %d times", status, attempts)
+		}
+	}
+}
+
+func TestDoRequestResendsGetOnGatewayErrors(t *testing.T) {
+	attempts := 0
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Write([]byte(`{"id":"order-1"}`))
+	})
+	c := newTestClient(srv, Config{MaxRetries: 3})
+
+	if _, err := c.GetOrder(context.Background(), "order-1"); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
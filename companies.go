@@ -1,6 +1,7 @@
 package melhorenvio
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -70,6 +71,9 @@ type Company struct {
 	TrackingLink      string `json:"tracking_link"`
 	UseOwnContract    bool   `json:"use_own_contract"`
 	BatchSize         int32  `json:"batch_size"`
+
+	// preenchido apenas na listagem/consulta de transportadoras
+	Services []Service `json:"services,omitempty"`
 }
 
 type Service struct {
@@ -85,15 +89,44 @@ type Service struct {
 }
 
 func (c *Client) GetServiceInfo(serviceId int32) (*Service, error) {
-	httpReq, err := http.NewRequestWithContext(c.context, "GET", c.config.ApiUrl+"/api/v2/me/shipment/services/"+strconv.FormatInt(int64(serviceId), 10), nil)
+	return c.GetServiceInfoContext(c.context, serviceId)
+}
+
+func (c *Client) GetServiceInfoContext(ctx context.Context, serviceId int32) (*Service, error) {
+	resp, _, _, err := getPublic[*Service](ctx, c, "/api/v2/me/shipment/services/"+strconv.FormatInt(int64(serviceId), 10), "")
+	return resp, err
+}
+
+func (c *Client) ListServices(ctx context.Context) ([]*Service, error) {
+	resp, _, _, err := getPublic[[]*Service](ctx, c, "/api/v2/me/shipment/services", "")
+	return resp, err
+}
+
+// ListCompanies retorna as transportadoras, cada uma com os seus serviços em Services
+func (c *Client) ListCompanies(ctx context.Context) ([]*Company, error) {
+	resp, _, _, err := getPublic[[]*Company](ctx, c, "/api/v2/me/shipment/companies", "")
+	return resp, err
+}
+
+func (c *Client) GetCompany(ctx context.Context, companyId int32) (*Company, error) {
+	resp, _, _, err := getPublic[*Company](ctx, c, "/api/v2/me/shipment/companies/"+strconv.FormatInt(int64(companyId), 10), "")
+	return resp, err
+}
+
+// faz um GET em uma rota pública da api (sem autenticação)
+// quando etag é informado, envia If-None-Match e retorna notModified caso a api responda 304
+func getPublic[T any](ctx context.Context, c *Client, path string, etag string) (resp T, newEtag string, notModified bool, err error) {
+	httpReq, err := c.newRequest(ctx, "GET", path, nil)
 	if err != nil {
-		return nil, err
+		return resp, "", false, err
+	}
+	if etag != "" {
+		httpReq.Header.Set("If-None-Match", etag)
 	}
 
-	// TODO executar a partir de outra função, que não envie os dados de autenticação, pois esta rota é pública
-	httpResp, err := c.doRequest(httpReq)
+	httpResp, err := c.doPublicRequest(httpReq)
 	if err != nil {
-		return nil, err
+		return resp, "", false, err
 	}
 	defer httpResp.Body.Close()
 
@@ -101,17 +134,15 @@ func (c *Client) GetServiceInfo(serviceId int32) (*Service, error) {
 
 	switch httpResp.StatusCode {
 	case http.StatusOK:
-		resp := &Service{}
-		err = json.Unmarshal(body, resp)
+		err = json.Unmarshal(body, &resp)
 		if err != nil {
-			return nil, err
+			return resp, "", false, fmt.Errorf("melhor envio: catalog: unrecognized response: %v %v", httpResp.StatusCode, string(body))
 		}
 
-		return resp, nil
-
-	// case http.StatusUnauthorized:
-	// 	return nil, ErrInvalidToken
+		return resp, httpResp.Header.Get("ETag"), false, nil
+	case http.StatusNotModified:
+		return resp, etag, true, nil
 	default:
-		return nil, fmt.Errorf("melhor envio: service: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return resp, "", false, parseAPIError(httpResp, body)
 	}
 }
@@ -1,9 +1,8 @@
 package melhorenvio
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 )
@@ -26,10 +25,24 @@ type Product struct {
 	Quantity       int32   `json:"quantity"`
 }
 
+// formato do volume, define quais restrições do serviço se aplicam (ver Validate)
+type PackageFormat string
+
+const (
+	PackageFormat_Box    PackageFormat = "box"
+	PackageFormat_Roll   PackageFormat = "roll"
+	PackageFormat_Letter PackageFormat = "letter"
+)
+
 type Volume struct {
 	Dimensions
 	Weight float64 `json:"weight"`
 
+	// usados apenas na validação local (Validate), vazio equivale a caixa
+	// no formato rolo, Diameter substitui a largura e a altura
+	Format   PackageFormat `json:"-"`
+	Diameter float64       `json:"-"`
+
 	// tá na api mas aparentemente não faz nada quando usado no cálculo de fretes, então removi do json, mas mantive aqui pra usar em lógicas internas em algumas aplicações
 	InsuranceValue float64 `json:"-"`
 }
@@ -92,22 +105,23 @@ type CotacaoResponse struct {
 }
 
 type CotacaoError struct {
-	Message string              `json:"message"`
-	Errors  map[string][]string `json:"errors"`
+	APIError
 }
 
 func (ce *CotacaoError) Error() string {
-	return "melhor envio: cotacao: " + ce.Message + ": " + fmt.Sprintf("%v", ce.Errors)
+	return "melhor envio: cotacao: " + ce.describe()
+}
+
+func (ce *CotacaoError) Unwrap() error {
+	return &ce.APIError
 }
 
 func (c *Client) CotarFrete(req *CotacaoRequest) ([]*CotacaoResponse, error) {
-	buf := &bytes.Buffer{}
-	err := json.NewEncoder(buf).Encode(req)
-	if err != nil {
-		return nil, err
-	}
+	return c.CotarFreteContext(c.context, req)
+}
 
-	httpReq, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/api/v2/me/shipment/calculate", buf)
+func (c *Client) CotarFreteContext(ctx context.Context, req *CotacaoRequest) ([]*CotacaoResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/calculate", req)
 	if err != nil {
 		return nil, err
 	}
@@ -129,17 +143,9 @@ func (c *Client) CotarFrete(req *CotacaoRequest) ([]*CotacaoResponse, error) {
 		}
 
 		return resp, nil
-	case http.StatusUnprocessableEntity:
-		ret := &CotacaoError{}
-		err = json.Unmarshal(body, ret)
-		if err != nil {
-			return nil, err
-		}
-		return nil, ret
-
 	case http.StatusUnauthorized:
 		return nil, ErrInvalidToken
 	default:
-		return nil, fmt.Errorf("melhor envio: cotacao: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return nil, &CotacaoError{APIError: *parseAPIError(httpResp, body)}
 	}
 }
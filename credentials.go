@@ -0,0 +1,245 @@
+package melhorenvio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// operação sobre as credenciais em andamento, compartilhada entre todas as chamadas
+// que precisarem do resultado da mesma operação
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// operações executadas via singleFlight, cada uma com a sua própria fila
+// tempo máximo de cada operação executada via singleFlight
+const credentialsTimeout = time.Minute
+
+const (
+	flightRefresh = "refresh"
+	flightLoad    = "load"
+	flightCode    = "code"
+)
+
+// Credentials retorna uma cópia das credenciais atuais do client
+func (c *Client) Credentials() Credentials {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.config.Credentials
+}
+
+func (c *Client) refreshSkew() time.Duration {
+	if c.config.RefreshSkew < 0 {
+		return 0
+	}
+	if c.config.RefreshSkew == 0 {
+		return defaultRefreshSkew
+	}
+	return c.config.RefreshSkew
+}
+
+// deve ser chamado com o mutex travado
+func (c *Client) tokenValidLocked() bool {
+	return c.config.Credentials.AccessToken != "" &&
+		time.Now().Add(c.refreshSkew()).Before(c.config.Credentials.ExpiresAt)
+}
+
+// retorna um access token válido, fazendo o refresh antes caso esteja expirado ou prestes a expirar
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	err := c.loadFromStore(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.mutex.Lock()
+	token := c.config.Credentials.AccessToken
+	valid := c.tokenValidLocked()
+	c.mutex.Unlock()
+
+	if valid {
+		return token, nil
+	}
+
+	return c.refresh(ctx, token)
+}
+
+// faz o refresh do token caso o token atual ainda seja o stale, ou seja, caso nenhuma
+// outra chamada já tenha atualizado o token nesse meio tempo
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.mutex.Lock()
+	if c.config.Credentials.AccessToken != stale && c.tokenValidLocked() {
+		token := c.config.Credentials.AccessToken
+		c.mutex.Unlock()
+		return token, nil
+	}
+	c.mutex.Unlock()
+
+	err := c.singleFlight(ctx, flightRefresh, c.refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	return c.Credentials().AccessToken, nil
+}
+
+// garante que apenas uma execução de cada operação (key) esteja em andamento por vez,
+// as chamadas concorrentes da mesma operação aguardam e recebem o resultado da que está em andamento,
+// evitando que o refresh token (que é rotacionado a cada uso) seja gasto mais de uma vez
+//
+// a operação roda em um contexto próprio (credentialsTimeout), e não no ctx de quem a iniciou: caso
+// esse ctx fosse cancelado depois que a api rotacionou o refresh token, mas antes da resposta ser lida,
+// os tokens novos se perderiam junto com o antigo. O ctx de cada chamada limita apenas a sua espera
+func (c *Client) singleFlight(ctx context.Context, key string, fn func(ctx context.Context) error) error {
+	c.inflight.Add(1)
+	defer c.inflight.Add(-1)
+
+	c.mutex.Lock()
+	call := c.flights[key]
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		if c.flights == nil {
+			c.flights = map[string]*refreshCall{}
+		}
+		c.flights[key] = call
+
+		c.inflight.Add(1)
+		go func() {
+			defer c.inflight.Add(-1)
+
+			flightCtx, cancel := context.WithTimeout(context.Background(), credentialsTimeout)
+			defer cancel()
+
+			call.err = fn(flightCtx)
+
+			c.mutex.Lock()
+			delete(c.flights, key)
+			c.mutex.Unlock()
+			close(call.done)
+		}()
+	}
+	c.mutex.Unlock()
+
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) lockStore(ctx context.Context) (func(), error) {
+	if c.config.TokenStore == nil {
+		return func() {}, nil
+	}
+
+	unlock, err := c.config.TokenStore.Lock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("melhor envio: token store: %w", err)
+	}
+	return unlock, nil
+}
+
+// notifica a atualização das credenciais, salvando no store (quando save for true) e chamando o callback
+// executado fora do mutex, dentro do singleFlight ou com o store travado (ver lockStore)
+func (c *Client) credentialsChanged(ctx context.Context, credentials Credentials, save bool) error {
+	if save && c.config.TokenStore != nil {
+		err := c.config.TokenStore.Save(ctx, credentials)
+		if err != nil {
+			return fmt.Errorf("melhor envio: token store: %w", err)
+		}
+	}
+
+	if c.config.CredentialsChangedCallback != nil {
+		return c.config.CredentialsChangedCallback(credentials)
+	}
+	return nil
+}
+
+// carrega as credenciais do store na primeira utilização do client
+func (c *Client) loadFromStore(ctx context.Context) error {
+	if c.config.TokenStore == nil {
+		return nil
+	}
+
+	for {
+		c.mutex.Lock()
+		loaded := c.storeLoaded
+		c.mutex.Unlock()
+
+		if loaded {
+			return nil
+		}
+
+		err := c.singleFlight(ctx, flightLoad, func(ctx context.Context) error {
+			c.mutex.Lock()
+			loaded := c.storeLoaded
+			c.mutex.Unlock()
+
+			if loaded {
+				return nil
+			}
+
+			_, err := c.reloadFromStore(ctx)
+			if errors.Is(err, ErrCredentialsNotFound) {
+				// store vazio, inicializa com as credenciais informadas no config
+				credentials := c.Credentials()
+				if credentials.RefreshToken != "" {
+					err = c.config.TokenStore.Save(ctx, credentials)
+				} else {
+					err = nil
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("melhor envio: token store: %w", err)
+			}
+
+			c.mutex.Lock()
+			c.storeLoaded = true
+			c.mutex.Unlock()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// substitui os tokens em memória pelos do store, caso sejam diferentes
+// retorna true se o token do store é válido e pode ser utilizado sem refresh
+func (c *Client) reloadFromStore(ctx context.Context) (bool, error) {
+	if c.config.TokenStore == nil {
+		return false, nil
+	}
+
+	stored, err := c.config.TokenStore.Load(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	c.mutex.Lock()
+	changed := stored.AccessToken != c.config.Credentials.AccessToken || stored.RefreshToken != c.config.Credentials.RefreshToken
+	if changed {
+		c.config.Credentials.AccessToken = stored.AccessToken
+		c.config.Credentials.RefreshToken = stored.RefreshToken
+		c.config.Credentials.ExpiresAt = stored.ExpiresAt
+		if stored.User != nil {
+			c.config.Credentials.User = stored.User
+		}
+	}
+	valid := c.tokenValidLocked()
+	credentials := c.config.Credentials
+	c.mutex.Unlock()
+
+	if changed {
+		err = c.credentialsChanged(ctx, credentials, false)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return changed && valid, nil
+}
@@ -0,0 +1,202 @@
+package melhorenvio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// servidor fake que conta os refreshes e aceita apenas o último access token emitido
+type tokenServer struct {
+	*httptest.Server
+	refreshes atomic.Int32
+	// atraso do /oauth/token, para que as chamadas concorrentes se acumulem
+	delay time.Duration
+	// fechado pelo teste para liberar o /oauth/token, quando não for nil
+	release chan struct{}
+}
+
+func newTokenServer(t *testing.T) *tokenServer {
+	t.Helper()
+
+	ts := &tokenServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			if ts.release != nil {
+				select {
+				case <-ts.release:
+				case <-r.Context().Done():
+					return
+				}
+			}
+			time.Sleep(ts.delay)
+			n := ts.refreshes.Add(1)
+			fmt.Fprintf(w, `{"access_token":"token-%d","refresh_token":"refresh-%d","expires_in":3600}`, n, n)
+			return
+		}
+
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", ts.refreshes.Load()) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func expiredClient(ts *tokenServer) *Client {
+	return NewClient(context.Background(), Config{
+		ApiUrl: ts.URL,
+		Credentials: Credentials{
+			AccessToken:  "token-0",
+			RefreshToken: "refresh-0",
+			ExpiresAt:    time.Now().Add(-time.Minute),
+		},
+	})
+}
+
+func TestParallelRequestsRefreshOnce(t *testing.T) {
+	ts := newTokenServer(t)
+	ts.delay = 20 * time.Millisecond
+	c := expiredClient(ts)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.CotarFrete(&CotacaoRequest{})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := ts.refreshes.Load(); n != 1 {
+		t.Fatalf("expected 1 refresh, got %d", n)
+	}
+	if c.Credentials().RefreshToken != "refresh-1" {
+		t.Fatalf("unexpected credentials: %+v", c.Credentials())
+	}
+}
+
+func TestRefreshSurvivesLeaderCancellation(t *testing.T) {
+	ts := newTokenServer(t)
+	ts.release = make(chan struct{})
+	c := expiredClient(ts)
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := c.CotarFreteContext(leaderCtx, &CotacaoRequest{})
+		leaderErr <- err
+	}()
+
+	// aguarda o líder iniciar o refresh antes de disparar a segunda chamada
+	waitFlight(t, c, flightRefresh)
+
+	followerErr := make(chan error, 1)
+	go func() {
+		_, err := c.CotarFreteContext(context.Background(), &CotacaoRequest{})
+		followerErr <- err
+	}()
+
+	// o líder desiste antes da resposta, mas a api rotaciona o refresh token mesmo assim
+	cancel()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected leader to be canceled, got %v", err)
+	}
+	close(ts.release)
+
+	if err := <-followerErr; err != nil {
+		t.Fatalf("follower failed after the leader was canceled: %v", err)
+	}
+	if credentials := c.Credentials(); credentials.AccessToken != "token-1" || credentials.RefreshToken != "refresh-1" {
+		t.Fatalf("rotated tokens were lost: %+v", credentials)
+	}
+
+	if _, err := c.CotarFrete(&CotacaoRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := ts.refreshes.Load(); n != 1 {
+		t.Fatalf("expected 1 refresh, got %d", n)
+	}
+}
+
+func TestRefreshKeepsTokensWithoutWaiters(t *testing.T) {
+	ts := newTokenServer(t)
+	ts.release = make(chan struct{})
+	c := expiredClient(ts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.RefreshTokenContext(ctx)
+	}()
+	waitFlight(t, c, flightRefresh)
+
+	cancel()
+	<-done
+	close(ts.release)
+
+	// ninguém mais aguarda o refresh, mas o resultado ainda deve ser aplicado
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Credentials().RefreshToken != "refresh-1" {
+		if time.Now().After(deadline) {
+			t.Fatalf("rotated tokens were lost: %+v", c.Credentials())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSingleFlightIsKeyedByOperation(t *testing.T) {
+	c := NewClient(context.Background(), Config{})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go c.singleFlight(context.Background(), flightRefresh, func(ctx context.Context) error {
+		close(started)
+		<-release
+		return errors.New("refresh failed")
+	})
+	<-started
+	defer close(release)
+
+	called := false
+	err := c.singleFlight(context.Background(), flightCode, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err != nil || !called {
+		t.Fatalf("code exchange shared the refresh flight: called=%v err=%v", called, err)
+	}
+}
+
+func waitFlight(t *testing.T, c *Client, key string) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		c.mutex.Lock()
+		inFlight := c.flights[key] != nil
+		c.mutex.Unlock()
+		if inFlight {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("flight %q never started", key)
+}
@@ -1,8 +1,156 @@
 package melhorenvio
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 var (
-	ErrClientNotInitialized = errors.New("melhor envio: client not initialized")
-	ErrInvalidToken         = errors.New("melhor envio: invalid token")
+	ErrClientNotInitialized  = errors.New("melhor envio: client not initialized")
+	ErrInvalidToken          = errors.New("melhor envio: invalid token")
+	ErrRequestNotReplayable  = errors.New("melhor envio: request body cannot be replayed")
+	ErrCredentialsNotFound   = errors.New("melhor envio: credentials not found")
+	ErrInvalidState          = errors.New("melhor envio: invalid oauth state")
+	ErrShipmentIncomplete    = errors.New("melhor envio: ship: some orders did not complete")
+	ErrLabelTooLarge         = errors.New("melhor envio: label: file too large")
+	ErrUnexpectedContentType = errors.New("melhor envio: label: unexpected content type")
+	ErrTenantStoreRequired   = errors.New("melhor envio: manager: tenant store required")
+	ErrEmptyWebhookSecret    = errors.New("melhor envio: webhook: empty secret")
+	ErrEmptyClientSecret     = errors.New("melhor envio: oauth: empty client secret")
+	ErrNilArgument           = errors.New("melhor envio: nil service or request")
+
+	// classificações de APIError, para uso com errors.Is
+	ErrInsufficientBalance = errors.New("melhor envio: insufficient balance")
+	ErrNotFound            = errors.New("melhor envio: not found")
+	ErrValidation          = errors.New("melhor envio: validation failed")
+	ErrRateLimited         = errors.New("melhor envio: rate limited")
 )
+
+// APIError representa uma resposta de erro da api
+// os erros específicos de cada operação (CartError, CotacaoError, etc) embutem este tipo
+type APIError struct {
+	StatusCode int
+	// método e caminho da requisição, ex: "POST /api/v2/me/cart"
+	Endpoint  string
+	RequestId string
+	Message   string
+	// erros por campo, ex: "to.postal_code": ["O campo to.postal_code é obrigatório."]
+	Errors map[string][]string
+	Body   []byte
+}
+
+func (e *APIError) Error() string {
+	return "melhor envio: " + e.Endpoint + ": " + e.describe()
+}
+
+func (e *APIError) describe() string {
+	if len(e.Errors) == 0 {
+		return e.Message
+	}
+	return e.Message + ": " + fmt.Sprintf("%v", e.Errors)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidToken:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrValidation:
+		return e.StatusCode == http.StatusUnprocessableEntity || e.StatusCode == http.StatusBadRequest
+	case ErrInsufficientBalance:
+		// a api responde 422 quando o saldo da carteira não cobre o pagamento, apenas nos endpoints
+		// que debitam a carteira, em outros endpoints "saldo" pode aparecer em erros não relacionados
+		if e.StatusCode != http.StatusUnprocessableEntity || !debitsWallet(e.Endpoint) {
+			return false
+		}
+		message := strings.ToLower(e.Message)
+		return strings.Contains(message, "saldo") || strings.Contains(message, "insufficient balance")
+	}
+	return false
+}
+
+// endpoints cujo erro de validação pode ser de saldo insuficiente
+var walletEndpoints = []string{
+	"POST /api/v2/me/shipment/checkout",
+	"POST /api/v2/me/balance",
+}
+
+func debitsWallet(endpoint string) bool {
+	for _, walletEndpoint := range walletEndpoints {
+		if endpoint == walletEndpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// monta o APIError a partir da resposta, aceitando as variações de formato da api
+// ("errors" ou "error", como mapa de campos ou como string)
+func parseAPIError(response *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: response.StatusCode,
+		RequestId:  response.Header.Get("X-Request-Id"),
+		Body:       body,
+	}
+	if response.Request != nil {
+		e.Endpoint = response.Request.Method + " " + response.Request.URL.Path
+	}
+
+	fields := map[string]json.RawMessage{}
+	if json.Unmarshal(body, &fields) == nil {
+		_ = json.Unmarshal(fields["message"], &e.Message)
+
+		for _, key := range []string{"errors", "error"} {
+			raw, ok := fields[key]
+			if !ok {
+				continue
+			}
+
+			var text string
+			if json.Unmarshal(raw, &text) == nil {
+				if e.Message == "" {
+					e.Message = text
+				}
+				continue
+			}
+			if errs := parseFieldErrors(raw); len(errs) > 0 {
+				e.Errors = errs
+			}
+		}
+	}
+
+	if e.Message == "" {
+		e.Message = fmt.Sprintf("unrecognized response: %v %v", response.StatusCode, string(body))
+	}
+
+	return e
+}
+
+func parseFieldErrors(raw json.RawMessage) map[string][]string {
+	var list map[string][]string
+	if json.Unmarshal(raw, &list) == nil {
+		return list
+	}
+
+	var single map[string]string
+	if json.Unmarshal(raw, &single) == nil {
+		list = make(map[string][]string, len(single))
+		for field, message := range single {
+			list[field] = []string{message}
+		}
+		return list
+	}
+
+	var messages []string
+	if json.Unmarshal(raw, &messages) == nil && len(messages) > 0 {
+		return map[string][]string{"": messages}
+	}
+
+	return nil
+}
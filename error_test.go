@@ -0,0 +1,29 @@
+package melhorenvio
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInsufficientBalance(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		endpoint   string
+		message    string
+		expected   bool
+	}{
+		{422, "POST /api/v2/me/shipment/checkout", "Saldo insuficiente", true},
+		{422, "POST /api/v2/me/shipment/checkout", "Insufficient balance", true},
+		{422, "POST /api/v2/me/shipment/checkout", "O campo orders é obrigatório", false},
+		{500, "POST /api/v2/me/shipment/checkout", "Erro ao consultar saldo", false},
+		{422, "POST /api/v2/me/cart", "Saldo insuficiente", false},
+		{404, "GET /api/v2/me/balance", "saldo", false},
+	}
+
+	for _, test := range tests {
+		err := error(&CheckoutError{APIError: APIError{StatusCode: test.statusCode, Endpoint: test.endpoint, Message: test.message}})
+		if errors.Is(err, ErrInsufficientBalance) != test.expected {
+			t.Errorf("%v %v %q: expected %v", test.statusCode, test.endpoint, test.message, test.expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package melhorenvio
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	fileStoreLockPoll = 50 * time.Millisecond
+
+	fileStoreKdfIterations = 100000
+	fileStoreSaltSize      = 16
+)
+
+// FileStore salva as credenciais em um arquivo json, com escrita atômica (arquivo temporário + rename)
+// a trava entre processos é feita sobre um arquivo ".lock" ao lado do arquivo de credenciais,
+// com flock nos sistemas unix (liberada pelo kernel caso o processo morra)
+type FileStore struct {
+	path       string
+	passphrase string
+}
+
+type fileStoreEnvelope struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// as credenciais são criptografadas com AES-GCM, usando uma chave derivada da passphrase (PBKDF2-SHA256)
+func NewEncryptedFileStore(path string, passphrase string) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+func (s *FileStore) Load(ctx context.Context) (Credentials, error) {
+	credentials := Credentials{}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return credentials, ErrCredentialsNotFound
+	}
+	if err != nil {
+		return credentials, err
+	}
+
+	if s.passphrase != "" {
+		data, err = s.decrypt(data)
+		if err != nil {
+			return credentials, err
+		}
+	}
+
+	err = json.Unmarshal(data, &credentials)
+	return credentials, err
+}
+
+func (s *FileStore) Save(ctx context.Context, credentials Credentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	if s.passphrase != "" {
+		data, err = s.encrypt(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *FileStore) Lock(ctx context.Context) (func(), error) {
+	lockPath := s.path + ".lock"
+
+	for {
+		unlock, err := tryLockFile(lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if unlock != nil {
+			var once sync.Once
+			return func() { once.Do(unlock) }, nil
+		}
+
+		timer := time.NewTimer(fileStoreLockPoll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *FileStore) encrypt(data []byte) ([]byte, error) {
+	envelope := &fileStoreEnvelope{
+		Salt: make([]byte, fileStoreSaltSize),
+	}
+	_, err := rand.Read(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope.Nonce = make([]byte, gcm.NonceSize())
+	_, err = rand.Read(envelope.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Data = gcm.Seal(nil, envelope.Nonce, data, nil)
+
+	return json.Marshal(envelope)
+}
+
+func (s *FileStore) decrypt(data []byte) ([]byte, error) {
+	envelope := &fileStoreEnvelope{}
+	err := json.Unmarshal(data, envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher(envelope.Salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(envelope.Nonce) != gcm.NonceSize() {
+		return nil, errors.New("melhor envio: file store: invalid nonce")
+	}
+
+	data, err = gcm.Open(nil, envelope.Nonce, envelope.Data, nil)
+	if err != nil {
+		return nil, errors.New("melhor envio: file store: invalid passphrase or corrupted file")
+	}
+	return data, nil
+}
+
+func (s *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2Key([]byte(s.passphrase), salt, fileStoreKdfIterations, 32)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// PBKDF2 (RFC 8018) com HMAC-SHA256, implementado aqui para não depender de golang.org/x/crypto
+func pbkdf2Key(password []byte, salt []byte, iterations int, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var counter [4]byte
+	key := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		key = prf.Sum(key)
+
+		t := key[len(key)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return key[:keyLen]
+}
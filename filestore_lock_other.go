@@ -0,0 +1,89 @@
+//go:build !unix
+
+package melhorenvio
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+const fileStoreLockStaleAge = 2 * time.Minute
+
+// sem flock, a trava é a existência do arquivo ".lock", criado com O_EXCL e contendo o pid
+// e um token aleatório que identificam o dono
+func tryLockFile(lockPath string) (func(), error) {
+	token, err := lockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err == nil {
+		_, err = f.Write(token)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(lockPath)
+			return nil, err
+		}
+
+		return func() {
+			// só remove se a trava ainda for nossa
+			if current, err := os.ReadFile(lockPath); err == nil && bytes.Equal(current, token) {
+				os.Remove(lockPath)
+			}
+		}, nil
+	}
+	if !errors.Is(err, os.ErrExist) {
+		return nil, err
+	}
+
+	// trava abandonada por um processo que morreu no meio do refresh
+	info, err := os.Stat(lockPath)
+	if err != nil || time.Since(info.ModTime()) <= fileStoreLockStaleAge {
+		return nil, nil
+	}
+	owner, err := os.ReadFile(lockPath)
+	if err != nil {
+		return nil, nil
+	}
+	breakStaleLock(lockPath, owner)
+	return nil, nil
+}
+
+// remove a trava apenas se ela ainda for a mesma que foi considerada abandonada
+// o arquivo é primeiro movido (rename é atômico) e só então comparado, caso outro processo
+// tenha recuperado a trava nesse meio tempo ela é devolvida sem sobrescrever uma trava nova
+func breakStaleLock(lockPath string, owner []byte) {
+	suffix, err := lockToken()
+	if err != nil {
+		return
+	}
+
+	stalePath := lockPath + "." + string(suffix[len(suffix)-16:]) + ".stale"
+	if os.Rename(lockPath, stalePath) != nil {
+		return
+	}
+	defer os.Remove(stalePath)
+
+	current, err := os.ReadFile(stalePath)
+	if err != nil || !bytes.Equal(current, owner) {
+		// Link falha caso já exista uma trava nova, então nunca a sobrescreve
+		os.Link(stalePath, lockPath)
+	}
+}
+
+func lockToken() ([]byte, error) {
+	random := make([]byte, 16)
+	_, err := rand.Read(random)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strconv.Itoa(os.Getpid()) + " " + hex.EncodeToString(random)), nil
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package melhorenvio
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// tenta obter a trava sem bloquear, retorna nil caso outro processo (ou outro Lock do mesmo
+// processo) a detenha
+//
+// o arquivo nunca é removido, a trava é do flock e não da existência do arquivo, assim não há
+// trava abandonada a recuperar quando um processo morre no meio do refresh
+func tryLockFile(lockPath string) (func(), error) {
+	f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		f.Close()
+		return nil, nil
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,123 @@
+package melhorenvio
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPbkdf2Key(t *testing.T) {
+	// vetores de teste do PBKDF2-HMAC-SHA256 (RFC 7914, seção 11)
+	tests := []struct {
+		password   string
+		salt       string
+		iterations int
+		keyLen     int
+		expected   string
+	}{
+		{"passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+		{"Password", "NaCl", 80000, 64, "4ddcd8f60b98be21830cee5ef22701f9641a4418d04c0414aeff08876b34ab56a1d425a1225833549adb841b51c9b3176a272bdebba1d078478f62b397f33c8d"},
+		{"passwd", "salt", 1, 20, "55ac046e56e3089fec1691c22544b605f9418521"},
+	}
+
+	for _, test := range tests {
+		key := pbkdf2Key([]byte(test.password), []byte(test.salt), test.iterations, test.keyLen)
+		if hex.EncodeToString(key) != test.expected {
+			t.Errorf("pbkdf2(%q, %q, %d, %d) = %x", test.password, test.salt, test.iterations, test.keyLen, key)
+		}
+	}
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	credentials := Credentials{AccessToken: "access", RefreshToken: "refresh", ExpiresAt: time.Now().Truncate(time.Second)}
+
+	store := NewEncryptedFileStore(path, "secret")
+	if _, err := store.Load(ctx); !errors.Is(err, ErrCredentialsNotFound) {
+		t.Fatalf("expected ErrCredentialsNotFound, got %v", err)
+	}
+	if err := store.Save(ctx, credentials); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("refresh")) {
+		t.Fatal("credentials stored in plain text")
+	}
+
+	loaded, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.RefreshToken != credentials.RefreshToken || !loaded.ExpiresAt.Equal(credentials.ExpiresAt) {
+		t.Fatalf("unexpected credentials: %+v", loaded)
+	}
+
+	if _, err := NewEncryptedFileStore(path, "wrong").Load(ctx); err == nil {
+		t.Fatal("expected error with the wrong passphrase")
+	}
+}
+
+func TestFileStoreLockIsExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	var mutex sync.Mutex
+	holders, maxHolders := 0, 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// um FileStore por goroutine, como se fossem processos diferentes
+			unlock, err := NewFileStore(path).Lock(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mutex.Lock()
+			holders++
+			if holders > maxHolders {
+				maxHolders = holders
+			}
+			mutex.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mutex.Lock()
+			holders--
+			mutex.Unlock()
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	if maxHolders != 1 {
+		t.Fatalf("lock held by %d callers at once", maxHolders)
+	}
+}
+
+func TestFileStoreLockHonorsContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	unlock, err := NewFileStore(path).Lock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := NewFileStore(path).Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+}
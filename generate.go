@@ -1,9 +1,8 @@
 package melhorenvio
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 )
@@ -18,22 +17,23 @@ type GenerateResponse struct {
 }
 
 type GenerateError struct {
-	Message string              `json:"message"`
-	Errors  map[string][]string `json:"errors"`
+	APIError
 }
 
 func (ge *GenerateError) Error() string {
-	return "melhor envio: generate: " + ge.Message + ": " + fmt.Sprintf("%v", ge.Errors)
+	return "melhor envio: generate: " + ge.describe()
+}
+
+func (ge *GenerateError) Unwrap() error {
+	return &ge.APIError
 }
 
 func (c *Client) Generate(req *GenerateRequest) (map[string]*GenerateResponse, error) {
-	buf := &bytes.Buffer{}
-	err := json.NewEncoder(buf).Encode(req)
-	if err != nil {
-		return nil, err
-	}
+	return c.GenerateContext(c.context, req)
+}
 
-	httpReq, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/api/v2/me/shipment/generate", buf)
+func (c *Client) GenerateContext(ctx context.Context, req *GenerateRequest) (map[string]*GenerateResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/generate", req)
 	if err != nil {
 		return nil, err
 	}
@@ -55,17 +55,9 @@ func (c *Client) Generate(req *GenerateRequest) (map[string]*GenerateResponse, e
 		}
 
 		return resp, nil
-	case http.StatusUnprocessableEntity:
-		ret := &GenerateError{}
-		err = json.Unmarshal(body, ret)
-		if err != nil {
-			return nil, err
-		}
-		return nil, ret
-
 	case http.StatusUnauthorized:
 		return nil, ErrInvalidToken
 	default:
-		return nil, fmt.Errorf("melhor envio: generate: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return nil, &GenerateError{APIError: *parseAPIError(httpResp, body)}
 	}
 }
@@ -0,0 +1,163 @@
+package melhorenvio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+)
+
+const defaultLabelMaxSize = 10 << 20
+
+type LabelOptions struct {
+	// tamanho máximo aceito (padrão de 10MB)
+	MaxSize int64
+	// content types aceitos (padrão application/pdf)
+	ContentTypes []string
+}
+
+type LabelInfo struct {
+	Url         string
+	ContentType string
+	Size        int64
+}
+
+func (o *LabelOptions) maxSize() int64 {
+	if o == nil || o.MaxSize <= 0 {
+		return defaultLabelMaxSize
+	}
+	return o.MaxSize
+}
+
+func (o *LabelOptions) contentTypes(format PrintFormat) []string {
+	if o != nil && len(o.ContentTypes) > 0 {
+		return o.ContentTypes
+	}
+	if format == PrintFormat_ZPL {
+		// não há um content type padrão para ZPL, cada servidor envia de uma forma
+		return []string{"application/zpl", "application/x-zpl", "text/plain", "application/octet-stream"}
+	}
+	return []string{"application/pdf"}
+}
+
+// DownloadLabel solicita a impressão e grava o arquivo da etiqueta em w.
+// No modo privado o download é feito com as credenciais do client, no público sem autenticação.
+// Caso o tamanho máximo seja excedido, parte do arquivo pode já ter sido escrita em w
+func (c *Client) DownloadLabel(ctx context.Context, req *PrintRequest, w io.Writer, opts *LabelOptions) (*LabelInfo, error) {
+	printed, err := c.PrintContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.download(ctx, printed.Url, req.Mode != Mode_Public, req.Format, w, opts)
+}
+
+// DownloadPreview solicita a pré-visualização e grava o pdf em w, nos mesmos moldes de DownloadLabel
+func (c *Client) DownloadPreview(ctx context.Context, orderIds []string, w io.Writer, opts *LabelOptions) (*LabelInfo, error) {
+	preview, err := c.Preview(ctx, orderIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.download(ctx, preview.Url, true, PrintFormat_PDF, w, opts)
+}
+
+// SaveLabel faz o download da etiqueta para o arquivo em path, que só é criado/substituído
+// quando o download é concluído com sucesso
+func (c *Client) SaveLabel(ctx context.Context, req *PrintRequest, path string, opts *LabelOptions) (*LabelInfo, error) {
+	return saveFile(path, func(w io.Writer) (*LabelInfo, error) {
+		return c.DownloadLabel(ctx, req, w, opts)
+	})
+}
+
+func saveFile(path string, download func(w io.Writer) (*LabelInfo, error)) (*LabelInfo, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	info, err := download(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *Client) download(ctx context.Context, url string, authenticated bool, format PrintFormat, w io.Writer, opts *LabelOptions) (*LabelInfo, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "*/*")
+
+	// o token só é enviado para o mesmo host da api
+	if apiUrl, err := neturl.Parse(c.config.ApiUrl); err != nil || apiUrl.Host != httpReq.URL.Host {
+		authenticated = false
+	}
+
+	httpResp, err := c.do(httpReq, authenticated)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(httpResp.Body, 4096))
+		return nil, &LabelError{APIError: *parseAPIError(httpResp, body)}
+	}
+
+	info := &LabelInfo{Url: url}
+	info.ContentType, _, _ = mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if !containsString(opts.contentTypes(format), info.ContentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedContentType, info.ContentType)
+	}
+
+	maxSize := opts.maxSize()
+	if httpResp.ContentLength > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrLabelTooLarge, httpResp.ContentLength)
+	}
+
+	info.Size, err = io.Copy(w, io.LimitReader(httpResp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if info.Size > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrLabelTooLarge, maxSize)
+	}
+
+	return info, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+type LabelError struct {
+	APIError
+}
+
+func (le *LabelError) Error() string {
+	return "melhor envio: label: " + le.describe()
+}
+
+func (le *LabelError) Unwrap() error {
+	return &le.APIError
+}
@@ -0,0 +1,261 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TenantStore persiste as credenciais de vários tenants (ex: sellers de um marketplace)
+// com a mesma semântica do TokenStore
+type TenantStore interface {
+	Load(ctx context.Context, tenantId string) (Credentials, error)
+	Save(ctx context.Context, tenantId string, credentials Credentials) error
+	Lock(ctx context.Context, tenantId string) (func(), error)
+}
+
+type ManagerConfig struct {
+	// configuração base dos clients, as credenciais de cada tenant vêm do Store
+	// (apenas ClientId e ClientSecret de Config.Credentials são utilizados)
+	Config Config
+
+	// obrigatório, é por ele que as credenciais de cada tenant são carregadas e que os clients
+	// (inclusive os já descartados e os de outros processos) coordenam o refresh
+	Store TenantStore
+
+	// tempo sem uso após o qual o client do tenant é descartado (zero nunca descarta)
+	IdleTimeout time.Duration
+
+	CredentialsChangedCallback func(tenantId string, credentials Credentials) error
+}
+
+type managedClient struct {
+	client   *Client
+	lastUsed time.Time
+}
+
+// Manager mantém um client por tenant, todos compartilhando o mesmo http.Client
+type Manager struct {
+	context context.Context
+
+	config ManagerConfig
+
+	mutex   sync.Mutex
+	clients map[string]*managedClient
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewManager(ctx context.Context, config ManagerConfig) (*Manager, error) {
+	if config.Store == nil {
+		return nil, ErrTenantStoreRequired
+	}
+
+	m := &Manager{}
+	m.context = ctx
+	m.config = config
+	if m.config.Config.HttpClient == nil {
+		m.config.Config.HttpClient = http.DefaultClient
+	}
+	m.clients = map[string]*managedClient{}
+	m.done = make(chan struct{})
+
+	if m.config.IdleTimeout > 0 {
+		go m.evictIdle()
+	}
+
+	return m, nil
+}
+
+// For retorna o client do tenant, criando-o na primeira utilização
+func (m *Manager) For(tenantId string) *Client {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	mc, ok := m.clients[tenantId]
+	if !ok {
+		mc = &managedClient{client: m.newClient(tenantId)}
+		m.clients[tenantId] = mc
+	}
+	mc.lastUsed = time.Now()
+
+	return mc.client
+}
+
+// SaveCredentials grava as credenciais do tenant no Store (ex: após a autorização do seller, ver CallbackHandler),
+// o client já existente do tenant passa a utilizá-las na próxima requisição
+func (m *Manager) SaveCredentials(ctx context.Context, tenantId string, credentials Credentials) error {
+	unlock, err := m.config.Store.Lock(ctx, tenantId)
+	if err != nil {
+		return fmt.Errorf("melhor envio: token store: %w", err)
+	}
+	err = m.config.Store.Save(ctx, tenantId, credentials)
+	unlock()
+	if err != nil {
+		return fmt.Errorf("melhor envio: token store: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if mc, ok := m.clients[tenantId]; ok {
+		mc.client.mutex.Lock()
+		mc.client.storeLoaded = false
+		mc.client.mutex.Unlock()
+	}
+	return nil
+}
+
+// Evict descarta o client do tenant, retorna false (sem descartar) caso ele tenha requisições em andamento
+func (m *Manager) Evict(tenantId string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	mc, ok := m.clients[tenantId]
+	if !ok {
+		return true
+	}
+	if mc.client.inflight.Load() > 0 {
+		return false
+	}
+
+	delete(m.clients, tenantId)
+	return true
+}
+
+// Close encerra a rotina de descarte de clients ociosos
+func (m *Manager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+func (m *Manager) newClient(tenantId string) *Client {
+	config := m.config.Config
+	config.Credentials = Credentials{
+		ClientId:     m.config.Config.Credentials.ClientId,
+		ClientSecret: m.config.Config.Credentials.ClientSecret,
+	}
+	config.TokenStore = &tenantTokenStore{store: m.config.Store, tenantId: tenantId}
+	if m.config.CredentialsChangedCallback != nil {
+		config.CredentialsChangedCallback = func(credentials Credentials) error {
+			return m.config.CredentialsChangedCallback(tenantId, credentials)
+		}
+	}
+
+	return NewClient(m.context, config)
+}
+
+func (m *Manager) evictIdle() {
+	ticker := time.NewTicker(m.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.context.Done():
+			return
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.mutex.Lock()
+			for tenantId, mc := range m.clients {
+				// um client com requisições em andamento pode estar no meio de um refresh,
+				// descartá-lo permitiria que um client novo gastasse o mesmo refresh token
+				if now.Sub(mc.lastUsed) > m.config.IdleTimeout && mc.client.inflight.Load() == 0 {
+					delete(m.clients, tenantId)
+				}
+			}
+			m.mutex.Unlock()
+		}
+	}
+}
+
+// adapta o TenantStore para o TokenStore de um único tenant
+type tenantTokenStore struct {
+	store    TenantStore
+	tenantId string
+}
+
+func (s *tenantTokenStore) Load(ctx context.Context) (Credentials, error) {
+	return s.store.Load(ctx, s.tenantId)
+}
+
+func (s *tenantTokenStore) Save(ctx context.Context, credentials Credentials) error {
+	return s.store.Save(ctx, s.tenantId, credentials)
+}
+
+func (s *tenantTokenStore) Lock(ctx context.Context) (func(), error) {
+	return s.store.Lock(ctx, s.tenantId)
+}
+
+type MemoryTenantStore struct {
+	mutex  sync.Mutex
+	stores map[string]*MemoryStore
+}
+
+func NewMemoryTenantStore() *MemoryTenantStore {
+	return &MemoryTenantStore{
+		stores: map[string]*MemoryStore{},
+	}
+}
+
+func (s *MemoryTenantStore) store(tenantId string) *MemoryStore {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	store, ok := s.stores[tenantId]
+	if !ok {
+		store = NewMemoryStore()
+		s.stores[tenantId] = store
+	}
+	return store
+}
+
+func (s *MemoryTenantStore) Load(ctx context.Context, tenantId string) (Credentials, error) {
+	return s.store(tenantId).Load(ctx)
+}
+
+func (s *MemoryTenantStore) Save(ctx context.Context, tenantId string, credentials Credentials) error {
+	return s.store(tenantId).Save(ctx, credentials)
+}
+
+func (s *MemoryTenantStore) Lock(ctx context.Context, tenantId string) (func(), error) {
+	return s.store(tenantId).Lock(ctx)
+}
+
+// FileTenantStore salva as credenciais de cada tenant em um FileStore próprio dentro de dir
+type FileTenantStore struct {
+	dir        string
+	passphrase string
+}
+
+func NewFileTenantStore(dir string) *FileTenantStore {
+	return &FileTenantStore{dir: dir}
+}
+
+func NewEncryptedFileTenantStore(dir string, passphrase string) *FileTenantStore {
+	return &FileTenantStore{dir: dir, passphrase: passphrase}
+}
+
+func (s *FileTenantStore) store(tenantId string) *FileStore {
+	// o id do tenant é codificado para não permitir caminhos arbitrários
+	path := filepath.Join(s.dir, base64.RawURLEncoding.EncodeToString([]byte(tenantId))+".json")
+	return &FileStore{path: path, passphrase: s.passphrase}
+}
+
+func (s *FileTenantStore) Load(ctx context.Context, tenantId string) (Credentials, error) {
+	return s.store(tenantId).Load(ctx)
+}
+
+func (s *FileTenantStore) Save(ctx context.Context, tenantId string, credentials Credentials) error {
+	return s.store(tenantId).Save(ctx, credentials)
+}
+
+func (s *FileTenantStore) Lock(ctx context.Context, tenantId string) (func(), error) {
+	return s.store(tenantId).Lock(ctx)
+}
@@ -0,0 +1,66 @@
+package melhorenvio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewManagerRequiresStore(t *testing.T) {
+	_, err := NewManager(context.Background(), ManagerConfig{})
+	if !errors.Is(err, ErrTenantStoreRequired) {
+		t.Fatalf("expected ErrTenantStoreRequired, got %v", err)
+	}
+}
+
+func TestManagerUsesSavedCredentials(t *testing.T) {
+	ts := newTokenServer(t)
+	m, err := NewManager(context.Background(), ManagerConfig{
+		Config: Config{ApiUrl: ts.URL},
+		Store:  NewMemoryTenantStore(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	c := m.For("seller")
+	err = m.SaveCredentials(context.Background(), "seller", Credentials{
+		AccessToken:  "token-0",
+		RefreshToken: "refresh-0",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.CotarFrete(&CotacaoRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Credentials().AccessToken != "token-0" {
+		t.Fatalf("client did not load the saved credentials: %+v", c.Credentials())
+	}
+}
+
+func TestManagerDoesNotEvictBusyClients(t *testing.T) {
+	m, err := NewManager(context.Background(), ManagerConfig{Store: NewMemoryTenantStore()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	c := m.For("seller")
+	c.inflight.Add(1)
+	if m.Evict("seller") {
+		t.Fatal("evicted a client with requests in flight")
+	}
+	if m.For("seller") != c {
+		t.Fatal("busy client was replaced")
+	}
+
+	c.inflight.Add(-1)
+	if !m.Evict("seller") || m.For("seller") == c {
+		t.Fatal("idle client was not evicted")
+	}
+}
@@ -0,0 +1,194 @@
+package melhorenvio
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Scope string
+
+const (
+	Scope_CartRead          Scope = "cart-read"
+	Scope_CartWrite         Scope = "cart-write"
+	Scope_CompaniesRead     Scope = "companies-read"
+	Scope_CompaniesWrite    Scope = "companies-write"
+	Scope_CouponsRead       Scope = "coupons-read"
+	Scope_CouponsWrite      Scope = "coupons-write"
+	Scope_NotificationsRead Scope = "notifications-read"
+	Scope_OrdersRead        Scope = "orders-read"
+	Scope_ProductsRead      Scope = "products-read"
+	Scope_ProductsWrite     Scope = "products-write"
+	Scope_PurchasesRead     Scope = "purchases-read"
+	Scope_ShippingCalculate Scope = "shipping-calculate"
+	Scope_ShippingCancel    Scope = "shipping-cancel"
+	Scope_ShippingCheckout  Scope = "shipping-checkout"
+	Scope_ShippingCompanies Scope = "shipping-companies"
+	Scope_ShippingGenerate  Scope = "shipping-generate"
+	Scope_ShippingPreview   Scope = "shipping-preview"
+	Scope_ShippingPrint     Scope = "shipping-print"
+	Scope_ShippingShare     Scope = "shipping-share"
+	Scope_ShippingTracking  Scope = "shipping-tracking"
+	Scope_EcommerceShipping Scope = "ecommerce-shipping"
+	Scope_TransactionsRead  Scope = "transactions-read"
+	Scope_UsersRead         Scope = "users-read"
+	Scope_UsersWrite        Scope = "users-write"
+	Scope_WebhooksRead      Scope = "webhooks-read"
+	Scope_WebhooksWrite     Scope = "webhooks-write"
+)
+
+const (
+	// validade do state gerado por NewState
+	StateMaxAge = 15 * time.Minute
+
+	stateNonceSize = 16
+)
+
+// AuthorizeURL monta a url para onde o usuário deve ser redirecionado para autorizar a aplicação
+func (c *Client) AuthorizeURL(scopes []Scope, state string) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+
+	query := url.Values{}
+	query.Set("client_id", strconv.FormatInt(int64(c.config.Credentials.ClientId), 10))
+	query.Set("redirect_uri", c.config.RedirectUri)
+	query.Set("response_type", "code")
+	query.Set("state", state)
+	query.Set("scope", strings.Join(names, " "))
+
+	return c.config.ApiUrl + "/oauth/authorize?" + query.Encode()
+}
+
+// NewState gera um state aleatório assinado com o ClientSecret e vinculado à sessão do usuário
+// (ex: o id da sessão guardado em cookie), que pode ser validado por VerifyState sem a necessidade
+// de guardar o próprio state. Sem o vínculo com a sessão o state não protege contra CSRF, já que
+// um state válido gerado para o atacante seria aceito no callback de qualquer usuário.
+// Retorna ErrEmptyClientSecret caso o ClientSecret não esteja configurado, já que qualquer um
+// poderia assinar um state com uma chave vazia
+func (c *Client) NewState(session string) (string, error) {
+	if c.config.Credentials.ClientSecret == "" {
+		return "", ErrEmptyClientSecret
+	}
+	if session == "" {
+		return "", ErrInvalidState
+	}
+
+	payload := make([]byte, stateNonceSize+8)
+	_, err := rand.Read(payload[:stateNonceSize])
+	if err != nil {
+		return "", err
+	}
+	binary.BigEndian.PutUint64(payload[stateNonceSize:], uint64(time.Now().Unix()))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.signState(payload, session)), nil
+}
+
+// VerifyState valida a assinatura, a sessão e a validade de um state gerado por NewState
+func (c *Client) VerifyState(state string, session string) error {
+	if c.config.Credentials.ClientSecret == "" {
+		return ErrEmptyClientSecret
+	}
+	if session == "" {
+		return ErrInvalidState
+	}
+
+	encodedPayload, encodedSignature, ok := strings.Cut(state, ".")
+	if !ok {
+		return ErrInvalidState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != stateNonceSize+8 {
+		return ErrInvalidState
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, c.signState(payload, session)) {
+		return ErrInvalidState
+	}
+
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(payload[stateNonceSize:])), 0)
+	if time.Since(issuedAt) > StateMaxAge {
+		return ErrInvalidState
+	}
+
+	return nil
+}
+
+// o payload tem tamanho fixo, então a concatenação com a sessão não é ambígua
+func (c *Client) signState(payload []byte, session string) []byte {
+	mac := hmac.New(sha256.New, []byte(c.config.Credentials.ClientSecret))
+	mac.Write(payload)
+	mac.Write([]byte(session))
+	return mac.Sum(nil)
+}
+
+// CallbackHandler recebe o redirecionamento do Melhor Envio na RedirectUri, valida o state contra a sessão
+// retornada por session (a mesma informada em NewState) e troca o code pelas credenciais via ExchangeCode.
+// As credenciais são apenas repassadas para onSuccess, cabe a ele gravá-las (ex: Manager.SaveCredentials
+// ou SetCredentials), de forma que o mesmo handler pode atender vários usuários em paralelo.
+// Caso onError seja nil, responde com 400 e uma mensagem genérica, já que o erro pode conter
+// a resposta da api
+func (c *Client) CallbackHandler(session func(r *http.Request) string, onSuccess func(w http.ResponseWriter, r *http.Request, credentials Credentials), onError func(w http.ResponseWriter, r *http.Request, err error)) http.Handler {
+	if session == nil {
+		panic("melhor envio: CallbackHandler: session is required")
+	}
+	if onSuccess == nil {
+		panic("melhor envio: CallbackHandler: onSuccess is required")
+	}
+	if onError == nil {
+		onError = func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, "authorization failed", http.StatusBadRequest)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		if oauthErr := query.Get("error"); oauthErr != "" {
+			onError(w, r, &OAuthError{Code: oauthErr, Description: query.Get("error_description")})
+			return
+		}
+
+		err := c.VerifyState(query.Get("state"), session(r))
+		if err != nil {
+			onError(w, r, err)
+			return
+		}
+
+		code := query.Get("code")
+		if code == "" {
+			onError(w, r, ErrInvalidToken)
+			return
+		}
+
+		credentials, err := c.ExchangeCode(r.Context(), code)
+		if err != nil {
+			onError(w, r, err)
+			return
+		}
+
+		onSuccess(w, r, credentials)
+	})
+}
+
+// erro retornado pelo Melhor Envio no redirecionamento (ex: usuário negou o acesso)
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (oe *OAuthError) Error() string {
+	if oe.Description == "" {
+		return "melhor envio: oauth: " + oe.Code
+	}
+	return "melhor envio: oauth: " + oe.Code + ": " + oe.Description
+}
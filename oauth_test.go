@@ -0,0 +1,261 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// servidor OAuth fake, emite tokens derivados do code ou do refresh token recebido
+func newOAuthServer(t *testing.T, block chan struct{}) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		aReq := authRequest{}
+		json.NewDecoder(r.Body).Decode(&aReq)
+
+		var token string
+		switch {
+		case aReq.GrantType == "authorization_code" && aReq.Code != "" && aReq.RedirectUri == "https://erp.example/callback":
+			token = "code-" + aReq.Code
+		case aReq.GrantType == "refresh_token" && aReq.RefreshToken != "":
+			if block != nil {
+				<-block
+			}
+			token = "refreshed"
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"access_token":  "access-" + token,
+			"refresh_token": "refresh-" + token,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newOAuthClient(srv *httptest.Server) *Client {
+	return NewClient(context.Background(), Config{
+		ApiUrl:      srv.URL,
+		RedirectUri: "https://erp.example/callback",
+		Credentials: Credentials{ClientId: 1, ClientSecret: "secret", RefreshToken: "refresh-0"},
+	})
+}
+
+func callback(t *testing.T, handler http.Handler, session string, query url.Values) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest("GET", "/callback?"+query.Encode(), nil)
+	r.Header.Set("X-Session", session)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestStateIsBoundToSession(t *testing.T) {
+	c := NewClient(context.Background(), Config{Credentials: Credentials{ClientId: 1, ClientSecret: "secret"}})
+
+	state, err := c.NewState("session-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.VerifyState(state, "session-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.VerifyState(state, "session-b"); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("state accepted for another session: %v", err)
+	}
+	if err := c.VerifyState(state, ""); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("state accepted without session: %v", err)
+	}
+	if _, err := c.NewState(""); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("state generated without session: %v", err)
+	}
+}
+
+func TestStateRequiresClientSecret(t *testing.T) {
+	c := NewClient(context.Background(), Config{Credentials: Credentials{ClientId: 1}})
+
+	if _, err := c.NewState("session"); !errors.Is(err, ErrEmptyClientSecret) {
+		t.Fatalf("expected ErrEmptyClientSecret, got %v", err)
+	}
+
+	// state assinado com a chave vazia, como faria qualquer um que conheça o formato
+	payload := make([]byte, stateNonceSize+8)
+	binary.BigEndian.PutUint64(payload[stateNonceSize:], uint64(time.Now().Unix()))
+	state := base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(c.signState(payload, "session"))
+	if err := c.VerifyState(state, "session"); !errors.Is(err, ErrEmptyClientSecret) {
+		t.Fatalf("expected ErrEmptyClientSecret, got %v", err)
+	}
+}
+
+func TestCallbackHandlerRequiresOnSuccess(t *testing.T) {
+	c := NewClient(context.Background(), Config{Credentials: Credentials{ClientId: 1, ClientSecret: "secret"}})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected CallbackHandler to panic without onSuccess")
+		}
+	}()
+	c.CallbackHandler(func(r *http.Request) string {
+		return "session"
+	}, nil, nil)
+}
+
+func TestCallbackHandlerDefaultErrorHidesDetails(t *testing.T) {
+	srv := newOAuthServer(t, nil)
+	c := newOAuthClient(srv)
+	// redirect_uri diferente da cadastrada, a api recusa a troca do code
+	c.config.RedirectUri = "https://other.example/callback"
+
+	handler := c.CallbackHandler(func(r *http.Request) string {
+		return "session"
+	}, func(w http.ResponseWriter, r *http.Request, credentials Credentials) {
+		t.Error("onSuccess called after a failed exchange")
+	}, nil)
+
+	state, _ := c.NewState("session")
+	w := callback(t, handler, "session", url.Values{"state": {state}, "code": {"xyz"}})
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %v", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "invalid_grant") {
+		t.Fatalf("api response leaked to the user: %q", w.Body.String())
+	}
+}
+
+func TestCallbackHandlerExchangesPerRequest(t *testing.T) {
+	srv := newOAuthServer(t, nil)
+	c := newOAuthClient(srv)
+
+	var mutex sync.Mutex
+	received := map[string]string{}
+	handler := c.CallbackHandler(func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}, func(w http.ResponseWriter, r *http.Request, credentials Credentials) {
+		mutex.Lock()
+		received[r.Header.Get("X-Session")] = credentials.AccessToken
+		mutex.Unlock()
+	}, nil)
+
+	var wg sync.WaitGroup
+	for _, seller := range []string{"a", "b", "c", "d"} {
+		state, err := c.NewState(seller)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func(seller, state string) {
+			defer wg.Done()
+			w := callback(t, handler, seller, url.Values{"state": {state}, "code": {seller}})
+			if w.Code != http.StatusOK {
+				t.Errorf("seller %v: %v %v", seller, w.Code, w.Body.String())
+			}
+		}(seller, state)
+	}
+	wg.Wait()
+
+	for _, seller := range []string{"a", "b", "c", "d"} {
+		if received[seller] != "access-code-"+seller {
+			t.Errorf("seller %v received %q", seller, received[seller])
+		}
+	}
+	if credentials := c.Credentials(); credentials.AccessToken != "" || credentials.Code != "" {
+		t.Fatalf("callback changed the client credentials: %+v", credentials)
+	}
+}
+
+func TestCallbackHandlerDuringRefresh(t *testing.T) {
+	block := make(chan struct{})
+	srv := newOAuthServer(t, block)
+	c := newOAuthClient(srv)
+
+	refreshed := make(chan error, 1)
+	go func() {
+		refreshed <- c.RefreshTokenContext(context.Background())
+	}()
+	waitFlight(t, c, flightRefresh)
+
+	var credentials Credentials
+	handler := c.CallbackHandler(func(r *http.Request) string {
+		return "session"
+	}, func(w http.ResponseWriter, r *http.Request, exchanged Credentials) {
+		credentials = exchanged
+	}, nil)
+
+	state, _ := c.NewState("session")
+	w := callback(t, handler, "session", url.Values{"state": {state}, "code": {"xyz"}})
+	if w.Code != http.StatusOK || credentials.AccessToken != "access-code-xyz" {
+		t.Fatalf("callback did not exchange its own code: %v %+v", w.Code, credentials)
+	}
+
+	close(block)
+	if err := <-refreshed; err != nil {
+		t.Fatal(err)
+	}
+	if c.Credentials().AccessToken != "access-refreshed" {
+		t.Fatalf("unexpected client credentials: %+v", c.Credentials())
+	}
+}
+
+func TestCallbackHandlerRejectsInvalidState(t *testing.T) {
+	srv := newOAuthServer(t, nil)
+	c := newOAuthClient(srv)
+
+	var gotErr error
+	handler := c.CallbackHandler(func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}, func(w http.ResponseWriter, r *http.Request, credentials Credentials) {
+		t.Error("onSuccess called with an invalid state")
+	}, func(w http.ResponseWriter, r *http.Request, err error) {
+		gotErr = err
+	})
+
+	// state emitido para a sessão do atacante e entregue à vítima
+	state, _ := c.NewState("attacker")
+	callback(t, handler, "victim", url.Values{"state": {state}, "code": {"attacker-code"}})
+	if !errors.Is(gotErr, ErrInvalidState) {
+		t.Fatalf("expected ErrInvalidState, got %v", gotErr)
+	}
+
+	callback(t, handler, "victim", url.Values{"error": {"access_denied"}})
+	oauthErr := &OAuthError{}
+	if !errors.As(gotErr, &oauthErr) || oauthErr.Code != "access_denied" {
+		t.Fatalf("expected OAuthError, got %v", gotErr)
+	}
+}
+
+func TestAutenticateByCode(t *testing.T) {
+	srv := newOAuthServer(t, nil)
+	c := newOAuthClient(srv)
+	c.config.Credentials.Code = "abc"
+
+	if err := c.AutenticateByCode(); err != nil {
+		t.Fatal(err)
+	}
+	if credentials := c.Credentials(); credentials.AccessToken != "access-code-abc" || credentials.Code != "" {
+		t.Fatalf("unexpected credentials: %+v", credentials)
+	}
+}
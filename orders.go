@@ -0,0 +1,141 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type OrderFilter struct {
+	Status      OrderStatus
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+
+	Page    int
+	PerPage int
+}
+
+func (f *OrderFilter) query() url.Values {
+	query := url.Values{}
+	if f == nil {
+		return query
+	}
+
+	if f.Status != "" {
+		query.Set("status", string(f.Status))
+	}
+	if !f.CreatedFrom.IsZero() {
+		query.Set("created_from", f.CreatedFrom.In(apiLocation).Format(apiTimeLayout))
+	}
+	if !f.CreatedTo.IsZero() {
+		query.Set("created_to", f.CreatedTo.In(apiLocation).Format(apiTimeLayout))
+	}
+	if f.Page > 0 {
+		query.Set("page", strconv.Itoa(f.Page))
+	}
+	if f.PerPage > 0 {
+		query.Set("per_page", strconv.Itoa(f.PerPage))
+	}
+	return query
+}
+
+type OrderError struct {
+	APIError
+}
+
+func (oe *OrderError) Error() string {
+	return "melhor envio: order: " + oe.describe()
+}
+
+func (oe *OrderError) Unwrap() error {
+	return &oe.APIError
+}
+
+func newOrderError(e *APIError) error {
+	return &OrderError{APIError: *e}
+}
+
+func (c *Client) GetOrder(ctx context.Context, orderId string) (*CartResponse, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me/orders/"+url.PathEscape(orderId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp *CartResponse
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: order: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newOrderError(parseAPIError(httpResp, body))
+	}
+}
+
+// ListOrders retorna uma única página de etiquetas, para percorrer todas as páginas use IterateOrders
+func (c *Client) ListOrders(ctx context.Context, filter *OrderFilter) (*Page[*CartResponse], error) {
+	return fetchPage[*CartResponse](ctx, c, ordersPath(filter), newOrderError)
+}
+
+func (c *Client) IterateOrders(ctx context.Context, filter *OrderFilter) *Iterator[*CartResponse] {
+	return newIterator(ctx, ordersPath(filter), func(ctx context.Context, path string) (*Page[*CartResponse], error) {
+		return fetchPage[*CartResponse](ctx, c, path, newOrderError)
+	})
+}
+
+func ordersPath(filter *OrderFilter) string {
+	path := "/api/v2/me/orders"
+	if query := filter.query(); len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// SearchOrders busca etiquetas por id, protocolo, código de rastreio ou nome do destinatário
+func (c *Client) SearchOrders(ctx context.Context, q string) ([]*CartResponse, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me/orders/search?"+url.Values{"q": {q}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp []*CartResponse
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: order: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newOrderError(parseAPIError(httpResp, body))
+	}
+}
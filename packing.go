@@ -0,0 +1,300 @@
+package melhorenvio
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PackingBox é uma caixa disponível para o empacotamento local (ver Pack)
+type PackingBox struct {
+	Name string
+	// dimensões internas, em cm
+	Dimensions
+	// peso máximo de conteúdo, em kg (zero não limita)
+	MaxWeight float64
+	// peso da caixa vazia, somado ao peso do volume
+	EmptyWeight float64
+}
+
+// PackedVolume é uma caixa com os produtos colocados nela
+type PackedVolume struct {
+	Box PackingBox
+	// produtos da caixa, agrupados pela posição na entrada de Pack (Quantity é a quantidade dentro desta caixa),
+	// então produtos distintos nunca são mesclados, mesmo com ID vazio ou repetido
+	Products []Product
+	// peso total, já incluindo o peso da caixa vazia
+	Weight         float64
+	InsuranceValue float64
+}
+
+func (v *PackedVolume) Volume() Volume {
+	return Volume{
+		Dimensions:     v.Box.Dimensions,
+		Weight:         v.Weight,
+		InsuranceValue: v.InsuranceValue,
+	}
+}
+
+func (v *PackedVolume) CartVolume() CartVolume {
+	return CartVolume{
+		Dimensions: v.Box.Dimensions,
+		Weight:     v.Weight,
+	}
+}
+
+func Volumes(packed []*PackedVolume) []Volume {
+	volumes := make([]Volume, len(packed))
+	for i, v := range packed {
+		volumes[i] = v.Volume()
+	}
+	return volumes
+}
+
+func CartVolumes(packed []*PackedVolume) []CartVolume {
+	volumes := make([]CartVolume, len(packed))
+	for i, v := range packed {
+		volumes[i] = v.CartVolume()
+	}
+	return volumes
+}
+
+type ProductDoesNotFitError struct {
+	ProductId string
+	// posição do produto na entrada de Pack
+	Index int
+}
+
+func (e *ProductDoesNotFitError) Error() string {
+	return fmt.Sprintf("melhor envio: packing: product %v (index %v) does not fit in any box", e.ProductId, e.Index)
+}
+
+// Pack distribui os produtos nas caixas disponíveis com uma heurística de bin packing 3D
+// (first fit decreasing com extreme points), considerando as 6 rotações de cada produto e
+// o peso máximo da caixa. O resultado é determinístico para a mesma entrada, então o mesmo
+// empacotamento pode ser usado na cotação (Volumes) e no carrinho (CartVolumes)
+func Pack(products []Product, boxes []PackingBox) ([]*PackedVolume, error) {
+	items := expandProducts(products)
+
+	// maiores primeiro, desempate pelo peso e pela posição na entrada para manter o resultado estável
+	sort.SliceStable(items, func(i, j int) bool {
+		vi, vj := volumeOf(items[i].Dimensions), volumeOf(items[j].Dimensions)
+		if vi != vj {
+			return vi > vj
+		}
+		if items[i].Weight != items[j].Weight {
+			return items[i].Weight > items[j].Weight
+		}
+		return items[i].index < items[j].index
+	})
+
+	sortedBoxes := append([]PackingBox{}, boxes...)
+	sort.SliceStable(sortedBoxes, func(i, j int) bool {
+		return volumeOf(sortedBoxes[i].Dimensions) < volumeOf(sortedBoxes[j].Dimensions)
+	})
+
+	var bins []*packingBin
+	for _, item := range items {
+		placed := false
+		for _, bin := range bins {
+			if bin.place(item) {
+				placed = true
+				break
+			}
+		}
+		if placed {
+			continue
+		}
+
+		// abre a maior caixa em que o produto cabe, para reduzir a quantidade de volumes,
+		// depois ela é trocada pela menor caixa que comporte o que foi colocado nela
+		for i := len(sortedBoxes) - 1; i >= 0; i-- {
+			bin := newPackingBin(sortedBoxes[i])
+			if bin.place(item) {
+				bins = append(bins, bin)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return nil, &ProductDoesNotFitError{ProductId: item.ID, Index: item.index}
+		}
+	}
+
+	packed := make([]*PackedVolume, len(bins))
+	for i, bin := range bins {
+		packed[i] = shrinkBin(bin, sortedBoxes).packedVolume()
+	}
+	return packed, nil
+}
+
+// tenta colocar os mesmos produtos em uma caixa menor
+func shrinkBin(bin *packingBin, sortedBoxes []PackingBox) *packingBin {
+	for _, box := range sortedBoxes {
+		if volumeOf(box.Dimensions) >= volumeOf(bin.box.Dimensions) {
+			break
+		}
+
+		smaller := newPackingBin(box)
+		fits := true
+		for _, placement := range bin.placements {
+			if !smaller.place(placement.item) {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return smaller
+		}
+	}
+	return bin
+}
+
+// unidade de um produto, identificada pela posição do produto na entrada de Pack
+type packingItem struct {
+	Product
+	index int
+}
+
+// separa os produtos em unidades (Quantity 1)
+func expandProducts(products []Product) []packingItem {
+	var items []packingItem
+	for index, product := range products {
+		quantity := product.Quantity
+		if quantity <= 0 {
+			quantity = 1
+		}
+
+		unit := product
+		unit.Quantity = 1
+		for i := int32(0); i < quantity; i++ {
+			items = append(items, packingItem{Product: unit, index: index})
+		}
+	}
+	return items
+}
+
+func volumeOf(d Dimensions) float64 {
+	return d.Width * d.Height * d.Length
+}
+
+type packingPoint struct {
+	x, y, z float64
+}
+
+type packingPlacement struct {
+	item    packingItem
+	point   packingPoint
+	w, h, l float64
+}
+
+type packingBin struct {
+	box        PackingBox
+	placements []packingPlacement
+	points     []packingPoint
+	weight     float64
+}
+
+func newPackingBin(box PackingBox) *packingBin {
+	return &packingBin{
+		box:    box,
+		points: []packingPoint{{}},
+	}
+}
+
+func (b *packingBin) place(item packingItem) bool {
+	if b.box.MaxWeight > 0 && b.weight+item.Weight > b.box.MaxWeight {
+		return false
+	}
+
+	for pi, point := range b.points {
+		for _, r := range rotations(item.Dimensions) {
+			w, h, l := r[0], r[1], r[2]
+			if point.x+w > b.box.Width || point.y+h > b.box.Height || point.z+l > b.box.Length {
+				continue
+			}
+			if b.overlaps(point, w, h, l) {
+				continue
+			}
+
+			b.placements = append(b.placements, packingPlacement{item: item, point: point, w: w, h: h, l: l})
+			b.weight += item.Weight
+
+			b.points = append(b.points[:pi:pi], b.points[pi+1:]...)
+			b.points = append(b.points,
+				packingPoint{point.x + w, point.y, point.z},
+				packingPoint{point.x, point.y + h, point.z},
+				packingPoint{point.x, point.y, point.z + l},
+			)
+			// prioriza os pontos mais ao fundo, embaixo e à esquerda
+			sort.SliceStable(b.points, func(i, j int) bool {
+				if b.points[i].z != b.points[j].z {
+					return b.points[i].z < b.points[j].z
+				}
+				if b.points[i].y != b.points[j].y {
+					return b.points[i].y < b.points[j].y
+				}
+				return b.points[i].x < b.points[j].x
+			})
+			return true
+		}
+	}
+	return false
+}
+
+func (b *packingBin) overlaps(p packingPoint, w float64, h float64, l float64) bool {
+	for _, o := range b.placements {
+		if p.x < o.point.x+o.w && o.point.x < p.x+w &&
+			p.y < o.point.y+o.h && o.point.y < p.y+h &&
+			p.z < o.point.z+o.l && o.point.z < p.z+l {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *packingBin) packedVolume() *PackedVolume {
+	v := &PackedVolume{
+		Box:    b.box,
+		Weight: b.weight + b.box.EmptyWeight,
+	}
+
+	products := map[int]int{}
+	for _, placement := range b.placements {
+		v.InsuranceValue += placement.item.InsuranceValue
+
+		if i, ok := products[placement.item.index]; ok {
+			v.Products[i].Quantity++
+			continue
+		}
+		products[placement.item.index] = len(v.Products)
+		v.Products = append(v.Products, placement.item.Product)
+	}
+	return v
+}
+
+// as 6 orientações possíveis (largura, altura, comprimento), sem repetições
+func rotations(d Dimensions) [][3]float64 {
+	all := [][3]float64{
+		{d.Width, d.Height, d.Length},
+		{d.Width, d.Length, d.Height},
+		{d.Height, d.Width, d.Length},
+		{d.Height, d.Length, d.Width},
+		{d.Length, d.Width, d.Height},
+		{d.Length, d.Height, d.Width},
+	}
+
+	var unique [][3]float64
+	for _, r := range all {
+		duplicated := false
+		for _, u := range unique {
+			if u == r {
+				duplicated = true
+				break
+			}
+		}
+		if !duplicated {
+			unique = append(unique, r)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,136 @@
+package melhorenvio
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var testBoxes = []PackingBox{
+	{Name: "P", Dimensions: Dimensions{Width: 10, Height: 10, Length: 10}, MaxWeight: 5, EmptyWeight: 0.1},
+	{Name: "M", Dimensions: Dimensions{Width: 20, Height: 20, Length: 20}, MaxWeight: 10, EmptyWeight: 0.2},
+	{Name: "G", Dimensions: Dimensions{Width: 40, Height: 30, Length: 30}, MaxWeight: 30, EmptyWeight: 0.5},
+}
+
+func packedQuantity(packed []*PackedVolume) int32 {
+	var total int32
+	for _, v := range packed {
+		for _, product := range v.Products {
+			total += product.Quantity
+		}
+	}
+	return total
+}
+
+func TestPackSmallestBox(t *testing.T) {
+	packed, err := Pack([]Product{
+		{ID: "a", Dimensions: Dimensions{Width: 5, Height: 5, Length: 5}, Weight: 0.5, Quantity: 8},
+	}, testBoxes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 8 cubos de 5cm ocupam exatamente a caixa P
+	if len(packed) != 1 || packed[0].Box.Name != "P" {
+		t.Fatalf("expected a single P box, got %+v", packed)
+	}
+	if math.Abs(packed[0].Weight-4.1) > 1e-9 || packed[0].Products[0].Quantity != 8 {
+		t.Fatalf("unexpected volume: %+v", packed[0])
+	}
+}
+
+func TestPackRotatesProducts(t *testing.T) {
+	// o produto só cabe no tubo em pé
+	boxes := []PackingBox{{Name: "tubo", Dimensions: Dimensions{Width: 5, Height: 50, Length: 5}}}
+	packed, err := Pack([]Product{
+		{ID: "poster", Dimensions: Dimensions{Width: 48, Height: 4, Length: 4}, Weight: 1},
+	}, boxes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packed) != 1 || packed[0].Box.Name != "tubo" {
+		t.Fatalf("expected the tube box, got %+v", packed)
+	}
+}
+
+func TestPackRespectsMaxWeight(t *testing.T) {
+	packed, err := Pack([]Product{
+		{ID: "heavy", Dimensions: Dimensions{Width: 5, Height: 5, Length: 5}, Weight: 20, Quantity: 3},
+	}, testBoxes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packed) != 3 {
+		t.Fatalf("expected 3 volumes, got %d", len(packed))
+	}
+	for _, v := range packed {
+		if v.Weight-v.Box.EmptyWeight > v.Box.MaxWeight {
+			t.Fatalf("box %v over its max weight: %v", v.Box.Name, v.Weight)
+		}
+	}
+}
+
+func TestPackDoesNotMergeDistinctProducts(t *testing.T) {
+	products := []Product{
+		{Dimensions: Dimensions{Width: 5, Height: 5, Length: 5}, Weight: 1, InsuranceValue: 10},
+		{Dimensions: Dimensions{Width: 4, Height: 4, Length: 4}, Weight: 2, InsuranceValue: 20},
+		{ID: "dup", Dimensions: Dimensions{Width: 3, Height: 3, Length: 3}, Weight: 0.5, Quantity: 2},
+		{ID: "dup", Dimensions: Dimensions{Width: 2, Height: 2, Length: 2}, Weight: 0.3},
+	}
+
+	packed, err := Pack(products, testBoxes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(packed) != 1 || len(packed[0].Products) != 4 {
+		t.Fatalf("expected 4 distinct products in one box, got %+v", packed)
+	}
+	if packedQuantity(packed) != 5 {
+		t.Fatalf("expected 5 units, got %d", packedQuantity(packed))
+	}
+	for _, product := range packed[0].Products {
+		if product.ID == "dup" && product.Width == 3 && product.Quantity != 2 {
+			t.Fatalf("unexpected quantity for the 3cm product: %+v", product)
+		}
+	}
+}
+
+func TestPackIsDeterministic(t *testing.T) {
+	products := []Product{
+		{ID: "a", Dimensions: Dimensions{Width: 12, Height: 8, Length: 15}, Weight: 2, Quantity: 3},
+		{ID: "b", Dimensions: Dimensions{Width: 6, Height: 6, Length: 6}, Weight: 1, Quantity: 5},
+		{ID: "c", Dimensions: Dimensions{Width: 30, Height: 20, Length: 10}, Weight: 4},
+	}
+
+	first, err := Pack(products, testBoxes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		again, err := Pack(products, testBoxes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(first, again) {
+			t.Fatal("different result for the same input")
+		}
+	}
+	if packedQuantity(first) != 9 {
+		t.Fatalf("expected 9 units, got %d", packedQuantity(first))
+	}
+}
+
+func TestPackProductDoesNotFit(t *testing.T) {
+	_, err := Pack([]Product{
+		{ID: "ok", Dimensions: Dimensions{Width: 1, Height: 1, Length: 1}},
+		{ID: "huge", Dimensions: Dimensions{Width: 50, Height: 50, Length: 50}},
+	}, testBoxes)
+
+	notFit := &ProductDoesNotFitError{}
+	if !errors.As(err, &notFit) || notFit.ProductId != "huge" || notFit.Index != 1 {
+		t.Fatalf("expected ProductDoesNotFitError for index 1, got %v", err)
+	}
+}
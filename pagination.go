@@ -0,0 +1,129 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Page é o formato de paginação utilizado pela api nas listagens
+type Page[T any] struct {
+	CurrentPage int    `json:"current_page"`
+	LastPage    int    `json:"last_page"`
+	PerPage     int    `json:"per_page"`
+	Total       int    `json:"total"`
+	From        int    `json:"from"`
+	To          int    `json:"to"`
+	NextPageUrl string `json:"next_page_url"`
+	PrevPageUrl string `json:"prev_page_url"`
+	Data        []T    `json:"data"`
+}
+
+func fetchPage[T any](ctx context.Context, c *Client, path string, newError func(*APIError) error) (*Page[T], error) {
+	httpReq, err := c.newRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp *Page[T]
+		err = json.Unmarshal(body, &resp)
+		if err != nil || resp == nil {
+			return nil, fmt.Errorf("melhor envio: page: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newError(parseAPIError(httpResp, body))
+	}
+}
+
+// Iterator percorre todas as páginas de uma listagem, seguindo o next_page_url
+//
+//	it := client.IterateOrders(ctx, filter)
+//	for it.Next() {
+//		order := it.Value()
+//	}
+//	if it.Err() != nil { ... }
+type Iterator[T any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, path string) (*Page[T], error)
+
+	next  string
+	page  *Page[T]
+	index int
+	value T
+	err   error
+}
+
+func newIterator[T any](ctx context.Context, path string, fetch func(ctx context.Context, path string) (*Page[T], error)) *Iterator[T] {
+	return &Iterator[T]{
+		ctx:   ctx,
+		fetch: fetch,
+		next:  path,
+	}
+}
+
+func (it *Iterator[T]) Next() bool {
+	for {
+		if it.page != nil && it.index < len(it.page.Data) {
+			it.value = it.page.Data[it.index]
+			it.index++
+			return true
+		}
+
+		if it.err != nil || it.next == "" {
+			return false
+		}
+
+		page, err := it.fetch(it.ctx, it.next)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		it.page = page
+		it.index = 0
+		it.next, it.err = nextPagePath(page.NextPageUrl)
+	}
+}
+
+// Page retorna a página atual, útil para obter o Total
+func (it *Iterator[T]) Page() *Page[T] {
+	return it.page
+}
+
+func (it *Iterator[T]) Value() T {
+	return it.value
+}
+
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// o next_page_url vem com a url completa, mas as requisições são sempre feitas contra o ApiUrl do client
+func nextPagePath(nextPageUrl string) (string, error) {
+	if nextPageUrl == "" {
+		return "", nil
+	}
+
+	u, err := url.Parse(nextPageUrl)
+	if err != nil {
+		return "", err
+	}
+	return u.RequestURI(), nil
+}
@@ -1,7 +1,7 @@
 package melhorenvio
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,9 +15,18 @@ const (
 	Mode_Public  Mode = "public"
 )
 
+type PrintFormat string
+
+const (
+	PrintFormat_PDF PrintFormat = "pdf"
+	// formato térmico (Zebra), para as transportadoras que suportam
+	PrintFormat_ZPL PrintFormat = "zpl"
+)
+
 type PrintRequest struct {
-	Mode   Mode     `json:"mode"`
-	Orders []string `json:"orders"`
+	Mode   Mode        `json:"mode"`
+	Format PrintFormat `json:"format,omitempty"`
+	Orders []string    `json:"orders"`
 }
 
 type PrintResponse struct {
@@ -25,22 +34,42 @@ type PrintResponse struct {
 }
 
 type PrintError struct {
-	Message string              `json:"message"`
-	Errors  map[string][]string `json:"errors"`
+	APIError
 }
 
 func (pe *PrintError) Error() string {
-	return "melhor envio: print: " + pe.Message
+	return "melhor envio: print: " + pe.describe()
+}
+
+func (pe *PrintError) Unwrap() error {
+	return &pe.APIError
+}
+
+// a pré-visualização é para uso interno (ex: conferência pelo operador antes do pagamento),
+// então é sempre solicitada no modo privado
+type previewRequest struct {
+	Mode   Mode     `json:"mode"`
+	Orders []string `json:"orders"`
+}
+
+type PreviewError struct {
+	APIError
+}
+
+func (pe *PreviewError) Error() string {
+	return "melhor envio: preview: " + pe.describe()
+}
+
+func (pe *PreviewError) Unwrap() error {
+	return &pe.APIError
 }
 
 func (c *Client) Print(req *PrintRequest) (*PrintResponse, error) {
-	buf := &bytes.Buffer{}
-	err := json.NewEncoder(buf).Encode(req)
-	if err != nil {
-		return nil, err
-	}
+	return c.PrintContext(c.context, req)
+}
 
-	httpReq, err := http.NewRequestWithContext(c.context, "POST", c.config.ApiUrl+"/api/v2/me/shipment/print", buf)
+func (c *Client) PrintContext(ctx context.Context, req *PrintRequest) (*PrintResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/print", req)
 	if err != nil {
 		return nil, err
 	}
@@ -62,17 +91,40 @@ func (c *Client) Print(req *PrintRequest) (*PrintResponse, error) {
 		}
 
 		return resp, nil
-	case http.StatusUnprocessableEntity, http.StatusBadRequest:
-		ret := &PrintError{}
-		err = json.Unmarshal(body, ret)
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &PrintError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
+
+// Preview retorna a url de pré-visualização das etiquetas, disponível antes do pagamento
+func (c *Client) Preview(ctx context.Context, orderIds []string) (*PrintResponse, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/preview", &previewRequest{Mode: Mode_Private, Orders: orderIds})
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp *PrintResponse
+		err = json.Unmarshal(body, &resp)
 		if err != nil {
-			return nil, fmt.Errorf("melhor envio: print: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+			return nil, fmt.Errorf("melhor envio: preview: unrecognized response: %v %v", httpResp.StatusCode, string(body))
 		}
-		return nil, ret
 
+		return resp, nil
 	case http.StatusUnauthorized:
 		return nil, ErrInvalidToken
 	default:
-		return nil, fmt.Errorf("melhor envio: print: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		return nil, &PreviewError{APIError: *parseAPIError(httpResp, body)}
 	}
 }
@@ -0,0 +1,58 @@
+package melhorenvio
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDownloadPreview(t *testing.T) {
+	var previewed previewRequest
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/me/shipment/preview":
+			json.NewDecoder(r.Body).Decode(&previewed)
+			if previewed.Orders[0] == "invalid" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"message":"Etiqueta não encontrada"}`))
+				return
+			}
+			w.Write([]byte(`{"url":"http://` + r.Host + `/preview/abc"}`))
+		case "/preview/abc":
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	c := newTestClient(srv, Config{})
+
+	buf := &bytes.Buffer{}
+	info, err := c.DownloadPreview(context.Background(), []string{"order-1", "order-2"}, buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previewed.Mode != Mode_Private || strings.Join(previewed.Orders, ",") != "order-1,order-2" {
+		t.Fatalf("unexpected preview request: %+v", previewed)
+	}
+	if buf.String() != "%PDF-1.4" || info.ContentType != "application/pdf" {
+		t.Fatalf("unexpected preview: %+v %q", info, buf.String())
+	}
+
+	_, err = c.Preview(context.Background(), []string{"invalid"})
+	previewErr := &PreviewError{}
+	if !errors.As(err, &previewErr) || !strings.HasPrefix(err.Error(), "melhor envio: preview: ") {
+		t.Fatalf("expected PreviewError, got %v", err)
+	}
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected the APIError classification to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package melhorenvio
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// porta padrão de impressão raw (JetDirect) das impressoras térmicas
+const defaultPrinterPort = "9100"
+
+// PrintToPrinter baixa a etiqueta em ZPL e envia para a impressora térmica em addr ("host" ou "host:porta")
+func (c *Client) PrintToPrinter(ctx context.Context, req *PrintRequest, addr string, opts *LabelOptions) (*LabelInfo, error) {
+	zplReq := *req
+	zplReq.Format = PrintFormat_ZPL
+
+	// a etiqueta é baixada por completo antes do envio, para não mandar um arquivo pela metade para a impressora
+	buf := &bytes.Buffer{}
+	info, err := c.DownloadLabel(ctx, &zplReq, buf, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = SendToPrinter(ctx, addr, buf)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// SendToPrinter envia os bytes (ZPL) diretamente para uma impressora de rede via TCP
+func SendToPrinter(ctx context.Context, addr string, r io.Reader) error {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, defaultPrinterPort)
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+
+	_, err = io.Copy(conn, r)
+	if err != nil {
+		return err
+	}
+
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		return tcpConn.CloseWrite()
+	}
+	return nil
+}
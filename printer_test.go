@@ -0,0 +1,130 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testZpl = "^XA^FO50,50^A0N,50,50^FDMelhor Envio^FS^XZ"
+
+// impressora fake: aceita uma conexão e devolve tudo o que recebeu até o fim da escrita
+func newTestPrinter(t *testing.T) (string, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		data, _ := io.ReadAll(conn)
+		received <- string(data)
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func TestSendToPrinter(t *testing.T) {
+	addr, received := newTestPrinter(t)
+
+	err := SendToPrinter(context.Background(), addr, strings.NewReader(testZpl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case data := <-received:
+		if data != testZpl {
+			t.Fatalf("printer received %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("printer received nothing")
+	}
+}
+
+func TestSendToPrinterConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	if err := SendToPrinter(context.Background(), addr, strings.NewReader(testZpl)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPrintToPrinter(t *testing.T) {
+	addr, received := newTestPrinter(t)
+
+	var printReq PrintRequest
+	var srvUrl string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/me/shipment/print":
+			json.NewDecoder(r.Body).Decode(&printReq)
+			json.NewEncoder(w).Encode(PrintResponse{Url: srvUrl + "/labels/order-1.zpl"})
+		case "/labels/order-1.zpl":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(testZpl))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	srvUrl = srv.URL
+	c := newTestClient(srv, Config{})
+
+	_, err := c.PrintToPrinter(context.Background(), &PrintRequest{Mode: Mode_Private, Orders: []string{"order-1"}}, addr, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printReq.Format != PrintFormat_ZPL {
+		t.Fatalf("label requested as %q", printReq.Format)
+	}
+
+	select {
+	case data := <-received:
+		if data != testZpl {
+			t.Fatalf("printer received %q", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("printer received nothing")
+	}
+}
+
+func TestPrintToPrinterDoesNotSendFailedDownloads(t *testing.T) {
+	addr, received := newTestPrinter(t)
+
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"order not generated"}`))
+	})
+	c := newTestClient(srv, Config{})
+
+	_, err := c.PrintToPrinter(context.Background(), &PrintRequest{Orders: []string{"order-1"}}, addr, nil)
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	select {
+	case data := <-received:
+		t.Fatalf("printer received %q", data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,105 @@
+package melhorenvio
+
+import "sort"
+
+const (
+	Requirement_Names     = "names"
+	Requirement_Addresses = "addresses"
+	Requirement_Documents = "documents"
+	Requirement_Invoice   = "invoice"
+	Requirement_Phones    = "phones"
+	Requirement_Emails    = "emails"
+	Requirement_Agency    = "agency"
+)
+
+type RequirementsOptions struct {
+	// transportadoras que exigem a agência de postagem (AddToCartRequest.Agency) mesmo sem listar o
+	// requisito "agency" em Service.Requirements, ex: as que só aceitam postagem em agência própria
+	AgencyCompanies []int32
+}
+
+// ValidateRequirements verifica se os campos exigidos por Service.Requirements estão preenchidos
+// no request, retornando um *ValidationError com cada campo faltante. Requisitos desconhecidos
+// são ignorados, ficando a validação final a cargo da api. Retorna ErrNilArgument caso service
+// ou req seja nil
+func ValidateRequirements(service *Service, req *AddToCartRequest, opts *RequirementsOptions) error {
+	if service == nil || req == nil {
+		return ErrNilArgument
+	}
+	if opts == nil {
+		opts = &RequirementsOptions{}
+	}
+
+	v := &validator{}
+
+	requirements := map[string]bool{}
+	for _, requirement := range service.Requirements {
+		requirements[requirement] = true
+	}
+	for _, companyId := range opts.AgencyCompanies {
+		if service.Company.ID == companyId {
+			requirements[Requirement_Agency] = true
+		}
+	}
+
+	// ordem fixa, para que as violações saiam sempre na mesma ordem
+	names := make([]string, 0, len(requirements))
+	for requirement := range requirements {
+		names = append(names, requirement)
+	}
+	sort.Strings(names)
+
+	for _, requirement := range names {
+		for _, side := range []struct {
+			field string
+			value *CartToFrom
+		}{
+			{"from", &req.From},
+			{"to", &req.To},
+		} {
+			v.checkRequirement(requirement, side.field, side.value)
+		}
+
+		switch requirement {
+		case Requirement_Invoice:
+			// envios não comerciais usam declaração de conteúdo no lugar da nota fiscal
+			if !req.Options.NonCommercial && req.Options.Invoice.Key == "" {
+				v.add("options.invoice.key", "required by %q", requirement)
+			}
+		case Requirement_Agency:
+			if req.Agency == 0 {
+				v.add("agency", "required by %q", requirement)
+			}
+		}
+	}
+
+	return v.err()
+}
+
+func (v *validator) checkRequirement(requirement string, field string, party *CartToFrom) {
+	required := func(name string, value string) {
+		if value == "" {
+			v.add(field+"."+name, "required by %q", requirement)
+		}
+	}
+
+	switch requirement {
+	case Requirement_Names:
+		required("name", party.Name)
+	case Requirement_Addresses:
+		required("address", party.Address)
+		required("number", party.Number)
+		required("district", party.District)
+		required("city", party.City)
+		required("state_abbr", party.StateAbbr)
+		required("postal_code", party.PostalCode)
+	case Requirement_Documents:
+		if party.Document == "" && party.CompanyDocument == "" {
+			v.add(field+".document", "required by %q (document or company_document)", requirement)
+		}
+	case Requirement_Phones:
+		required("phone", party.Phone)
+	case Requirement_Emails:
+		required("email", party.Email)
+	}
+}
@@ -0,0 +1,41 @@
+package melhorenvio
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateRequirements(t *testing.T) {
+	service := &Service{Requirements: []string{Requirement_Names, Requirement_Invoice}, Company: Company{ID: 2}}
+	req := &AddToCartRequest{From: CartToFrom{Name: "Loja"}}
+
+	err := ValidateRequirements(service, req, nil)
+	expected := []string{"options.invoice.key", "to.name"}
+	if fields := violationFields(err); !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("expected %v, got %v", expected, fields)
+	}
+
+	// a agência só é exigida quando a transportadora é informada nas opções
+	err = ValidateRequirements(service, req, &RequirementsOptions{AgencyCompanies: []int32{2}})
+	expected = []string{"agency", "options.invoice.key", "to.name"}
+	if fields := violationFields(err); !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("expected %v, got %v", expected, fields)
+	}
+
+	req.To.Name = "Cliente"
+	req.Options.NonCommercial = true
+	req.Agency = 10
+	if err := ValidateRequirements(service, req, &RequirementsOptions{AgencyCompanies: []int32{2}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateRequirementsNilArguments(t *testing.T) {
+	if err := ValidateRequirements(nil, &AddToCartRequest{}, nil); !errors.Is(err, ErrNilArgument) {
+		t.Fatalf("expected ErrNilArgument, got %v", err)
+	}
+	if err := ValidateRequirements(&Service{}, nil, nil); !errors.Is(err, ErrNilArgument) {
+		t.Fatalf("expected ErrNilArgument, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package melhorenvio
+
+import (
+	"context"
+	"time"
+)
+
+type ShipStep string
+
+const (
+	ShipStep_Cart     ShipStep = "cart"
+	ShipStep_Checkout ShipStep = "checkout"
+	ShipStep_Generate ShipStep = "generate"
+	ShipStep_Print    ShipStep = "print"
+)
+
+const (
+	defaultCancelDescription   = "Falha na geração da etiqueta"
+	defaultCompensationTimeout = 2 * time.Minute
+)
+
+type ShipOptions struct {
+	// desfaz os passos já executados das etiquetas que falharam:
+	// RemoveFromCart para as não pagas e Cancel para as pagas que não foram geradas
+	Compensate bool
+	// motivo e descrição do cancelamento das etiquetas pagas que não foram geradas, por padrão
+	// CancelReason_Customer (cancelamento solicitado pelo titular da conta) com a descrição da falha
+	CancelReason      CancelReason
+	CancelDescription string
+	// tempo máximo da compensação (padrão de 2 minutos), que roda em um contexto próprio para que
+	// um ctx cancelado ou expirado (muitas vezes a própria causa da falha) não impeça o desfazimento
+	CompensationTimeout time.Duration
+
+	SkipPrint bool
+	PrintMode Mode
+}
+
+type ShipOrderResult struct {
+	Request *AddToCartRequest
+	Order   *CartResponse
+
+	// último passo concluído com sucesso (vazio se nem foi adicionada ao carrinho)
+	Step ShipStep
+	// erro do passo seguinte a Step, nil quando todo o fluxo foi concluído
+	Err error
+
+	Compensated     bool
+	CompensationErr error
+}
+
+type ShipResult struct {
+	Orders   []*ShipOrderResult
+	Checkout *CheckoutResponse
+	// url de impressão das etiquetas geradas
+	Print *PrintResponse
+}
+
+// Ship executa AddToCart, Checkout, Generate e Print em sequência.
+// O resultado traz a situação de cada etiqueta, na mesma ordem de reqs, e o erro
+// retornado é ErrShipmentIncomplete quando alguma delas não concluiu o fluxo
+func (c *Client) Ship(ctx context.Context, reqs []AddToCartRequest, opts *ShipOptions) (*ShipResult, error) {
+	if opts == nil {
+		opts = &ShipOptions{}
+	}
+
+	result := &ShipResult{
+		Orders: make([]*ShipOrderResult, len(reqs)),
+	}
+
+	var carted []*ShipOrderResult
+	for i := range reqs {
+		order := &ShipOrderResult{Request: &reqs[i]}
+		result.Orders[i] = order
+
+		order.Order, order.Err = c.AddToCartContext(ctx, order.Request)
+		if order.Err != nil {
+			continue
+		}
+		order.Step = ShipStep_Cart
+		carted = append(carted, order)
+	}
+
+	if len(carted) > 0 {
+		checkout, err := c.CheckoutContext(ctx, &CheckoutRequest{Orders: shipOrderIds(carted)})
+		if err != nil {
+			for _, order := range carted {
+				order.Err = err
+			}
+		} else {
+			result.Checkout = checkout
+			for _, order := range carted {
+				order.Step = ShipStep_Checkout
+			}
+		}
+	}
+
+	paid := shipOrdersAt(result.Orders, ShipStep_Checkout)
+	if len(paid) > 0 {
+		generated, err := c.GenerateContext(ctx, &GenerateRequest{Orders: shipOrderIds(paid)})
+		for _, order := range paid {
+			switch {
+			case err != nil:
+				order.Err = err
+			case generated[order.Order.Id] == nil:
+				order.Err = &ShipOrderError{Step: ShipStep_Generate, Message: "order not present in response"}
+			case !generated[order.Order.Id].Status:
+				order.Err = &ShipOrderError{Step: ShipStep_Generate, Message: generated[order.Order.Id].Message}
+			default:
+				order.Step = ShipStep_Generate
+			}
+		}
+	}
+
+	generated := shipOrdersAt(result.Orders, ShipStep_Generate)
+	if len(generated) > 0 && !opts.SkipPrint {
+		mode := opts.PrintMode
+		if mode == "" {
+			mode = Mode_Private
+		}
+
+		// falha na impressão não é compensada, a etiqueta já gerada pode ser impressa depois
+		printed, err := c.PrintContext(ctx, &PrintRequest{Mode: mode, Orders: shipOrderIds(generated)})
+		for _, order := range generated {
+			if err != nil {
+				order.Err = err
+			} else {
+				order.Step = ShipStep_Print
+			}
+		}
+		result.Print = printed
+	}
+
+	if opts.Compensate {
+		c.compensate(result.Orders, opts)
+	}
+
+	for _, order := range result.Orders {
+		if order.Err != nil {
+			return result, ErrShipmentIncomplete
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) compensate(orders []*ShipOrderResult, opts *ShipOptions) {
+	reason := opts.CancelReason
+	if reason == "" {
+		reason = CancelReason_Customer
+	}
+	description := opts.CancelDescription
+	if description == "" {
+		description = defaultCancelDescription
+	}
+	timeout := opts.CompensationTimeout
+	if timeout <= 0 {
+		timeout = defaultCompensationTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, order := range orders {
+		if order.Err == nil {
+			continue
+		}
+
+		switch order.Step {
+		case ShipStep_Cart:
+			order.CompensationErr = c.RemoveFromCartContext(ctx, order.Order.Id)
+		case ShipStep_Checkout:
+			// sem a consulta de estorno do Cancel, o sucesso depende apenas do cancelamento
+			var canceled *CancelResponse
+			canceled, order.CompensationErr = c.cancel(ctx, order.Order.Id, reason, description)
+			if order.CompensationErr == nil && !canceled.Canceled {
+				order.CompensationErr = &ShipOrderError{Step: ShipStep_Checkout, Message: "order not canceled"}
+			}
+		default:
+			continue
+		}
+		order.Compensated = order.CompensationErr == nil
+	}
+}
+
+func shipOrderIds(orders []*ShipOrderResult) []string {
+	ids := make([]string, len(orders))
+	for i, order := range orders {
+		ids[i] = order.Order.Id
+	}
+	return ids
+}
+
+func shipOrdersAt(orders []*ShipOrderResult, step ShipStep) []*ShipOrderResult {
+	var ret []*ShipOrderResult
+	for _, order := range orders {
+		if order.Step == step && order.Err == nil {
+			ret = append(ret, order)
+		}
+	}
+	return ret
+}
+
+// erro de uma etiqueta específica dentro de um passo executado em lote
+type ShipOrderError struct {
+	Step    ShipStep
+	Message string
+}
+
+func (se *ShipOrderError) Error() string {
+	return "melhor envio: ship: " + string(se.Step) + ": " + se.Message
+}
@@ -0,0 +1,278 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// servidor fake do fluxo de envio, as etiquetas recebem o id "order-" + nome do destinatário
+type shipServer struct {
+	mutex sync.Mutex
+
+	// destinatários cujo AddToCart falha
+	cartFail map[string]bool
+	// etiquetas que o Generate não consegue gerar (status false)
+	generateFail map[string]bool
+	checkoutFail bool
+	printFail    bool
+	// chamado durante o Generate, que então aguarda o cancelamento do request
+	onGenerate func()
+
+	removed  []string
+	canceled []string
+	reasons  []CancelReason
+	lookups  int
+}
+
+func (s *shipServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/api/v2/me/cart":
+		req := AddToCartRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		if s.cartFail[req.To.Name] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"The given data was invalid.","errors":{"to.postal_code":["CEP inválido"]}}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CartResponse{Id: "order-" + req.To.Name})
+
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v2/me/cart/"):
+		s.removed = append(s.removed, strings.TrimPrefix(r.URL.Path, "/api/v2/me/cart/"))
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.URL.Path == "/api/v2/me/shipment/checkout":
+		if s.checkoutFail {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"error":"Saldo insuficiente"}`))
+			return
+		}
+		w.Write([]byte(`{"purchase":{"id":"purchase-1","status":"paid"}}`))
+
+	case r.URL.Path == "/api/v2/me/shipment/generate":
+		if s.onGenerate != nil {
+			// o servidor só percebe a conexão encerrada depois de consumir o corpo
+			io.Copy(io.Discard, r.Body)
+			s.onGenerate()
+			s.mutex.Unlock()
+			<-r.Context().Done()
+			s.mutex.Lock()
+			return
+		}
+
+		req := GenerateRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]*GenerateResponse{}
+		for _, orderId := range req.Orders {
+			if s.generateFail[orderId] {
+				resp[orderId] = &GenerateResponse{Status: false, Message: "Erro ao gerar etiqueta"}
+			} else {
+				resp[orderId] = &GenerateResponse{Status: true, Message: "Envio gerado"}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case r.URL.Path == "/api/v2/me/shipment/print":
+		if s.printFail {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Server Error"}`))
+			return
+		}
+		w.Write([]byte(`{"url":"https://melhorenvio.example/imprimir/abc"}`))
+
+	case r.URL.Path == "/api/v2/me/shipment/cancel":
+		req := cancelRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		s.canceled = append(s.canceled, req.Order.Id)
+		s.reasons = append(s.reasons, req.Order.ReasonId)
+		json.NewEncoder(w).Encode(map[string]*CancelResponse{req.Order.Id: {Canceled: true}})
+
+	case strings.HasPrefix(r.URL.Path, "/api/v2/me/orders/"):
+		// Cancel consulta o estorno, a compensação não deve depender dessa consulta
+		s.lookups++
+		w.WriteHeader(http.StatusInternalServerError)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newShipClient(t *testing.T, s *shipServer) *Client {
+	srv := newTestServer(t, s.handler)
+	return newTestClient(srv, Config{})
+}
+
+func shipRequests(names ...string) []AddToCartRequest {
+	reqs := make([]AddToCartRequest, len(names))
+	for i, name := range names {
+		reqs[i] = AddToCartRequest{Service: 1, To: CartToFrom{Name: name}}
+	}
+	return reqs
+}
+
+func shipSteps(result *ShipResult) []ShipStep {
+	steps := make([]ShipStep, len(result.Orders))
+	for i, order := range result.Orders {
+		steps[i] = order.Step
+	}
+	return steps
+}
+
+func assertSteps(t *testing.T, result *ShipResult, expected ...ShipStep) {
+	t.Helper()
+
+	steps := shipSteps(result)
+	for i := range expected {
+		if steps[i] != expected[i] {
+			t.Fatalf("expected steps %v, got %v", expected, steps)
+		}
+	}
+}
+
+func assertIds(t *testing.T, name string, ids []string, expected ...string) {
+	t.Helper()
+
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v %v, got %v", name, expected, ids)
+	}
+}
+
+func TestShip(t *testing.T) {
+	s := &shipServer{}
+	c := newShipClient(t, s)
+
+	result, err := c.Ship(context.Background(), shipRequests("a", "b"), &ShipOptions{Compensate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSteps(t, result, ShipStep_Print, ShipStep_Print)
+	if result.Checkout == nil || result.Print == nil || result.Print.Url == "" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	assertIds(t, "removed", s.removed)
+	assertIds(t, "canceled", s.canceled)
+}
+
+func TestShipPartialCartFailure(t *testing.T) {
+	s := &shipServer{cartFail: map[string]bool{"b": true}}
+	c := newShipClient(t, s)
+
+	result, err := c.Ship(context.Background(), shipRequests("a", "b", "c"), &ShipOptions{Compensate: true})
+	if !errors.Is(err, ErrShipmentIncomplete) {
+		t.Fatalf("expected ErrShipmentIncomplete, got %v", err)
+	}
+
+	// as demais etiquetas seguem o fluxo normalmente
+	assertSteps(t, result, ShipStep_Print, "", ShipStep_Print)
+	if !errors.Is(result.Orders[1].Err, ErrValidation) || result.Orders[1].Order != nil {
+		t.Fatalf("unexpected cart failure: %+v", result.Orders[1])
+	}
+	// nada a compensar para uma etiqueta que nem chegou ao carrinho
+	if result.Orders[1].Compensated || result.Orders[1].CompensationErr != nil {
+		t.Fatalf("unexpected compensation: %+v", result.Orders[1])
+	}
+	assertIds(t, "removed", s.removed)
+	assertIds(t, "canceled", s.canceled)
+}
+
+func TestShipCheckoutFailureRemovesFromCart(t *testing.T) {
+	s := &shipServer{checkoutFail: true}
+	c := newShipClient(t, s)
+
+	result, err := c.Ship(context.Background(), shipRequests("a", "b"), &ShipOptions{Compensate: true})
+	if !errors.Is(err, ErrShipmentIncomplete) {
+		t.Fatalf("expected ErrShipmentIncomplete, got %v", err)
+	}
+
+	assertSteps(t, result, ShipStep_Cart, ShipStep_Cart)
+	for _, order := range result.Orders {
+		if !errors.Is(order.Err, ErrInsufficientBalance) || !order.Compensated {
+			t.Fatalf("unexpected order result: %+v", order)
+		}
+	}
+	assertIds(t, "removed", s.removed, "order-a", "order-b")
+	assertIds(t, "canceled", s.canceled)
+}
+
+func TestShipGenerateFailureCancelsOnlyThatOrder(t *testing.T) {
+	s := &shipServer{generateFail: map[string]bool{"order-b": true}}
+	c := newShipClient(t, s)
+
+	result, err := c.Ship(context.Background(), shipRequests("a", "b", "c"), &ShipOptions{Compensate: true})
+	if !errors.Is(err, ErrShipmentIncomplete) {
+		t.Fatalf("expected ErrShipmentIncomplete, got %v", err)
+	}
+
+	assertSteps(t, result, ShipStep_Print, ShipStep_Checkout, ShipStep_Print)
+	failed := result.Orders[1]
+	shipErr := &ShipOrderError{}
+	if !errors.As(failed.Err, &shipErr) || shipErr.Step != ShipStep_Generate {
+		t.Fatalf("unexpected generate failure: %v", failed.Err)
+	}
+
+	// cancelada com sucesso, mesmo com a consulta de estorno falhando
+	if !failed.Compensated || failed.CompensationErr != nil {
+		t.Fatalf("order not compensated: %+v", failed)
+	}
+	assertIds(t, "canceled", s.canceled, "order-b")
+	assertIds(t, "removed", s.removed)
+	if s.reasons[0] != CancelReason_Customer || s.lookups != 0 {
+		t.Fatalf("unexpected cancellation: reasons %v, lookups %d", s.reasons, s.lookups)
+	}
+}
+
+func TestShipPrintFailureIsNotCompensated(t *testing.T) {
+	s := &shipServer{printFail: true}
+	c := newShipClient(t, s)
+
+	result, err := c.Ship(context.Background(), shipRequests("a", "b"), &ShipOptions{Compensate: true})
+	if !errors.Is(err, ErrShipmentIncomplete) {
+		t.Fatalf("expected ErrShipmentIncomplete, got %v", err)
+	}
+
+	// as etiquetas já geradas podem ser impressas depois
+	assertSteps(t, result, ShipStep_Generate, ShipStep_Generate)
+	for _, order := range result.Orders {
+		if order.Err == nil || order.Compensated || order.CompensationErr != nil {
+			t.Fatalf("unexpected order result: %+v", order)
+		}
+	}
+	assertIds(t, "removed", s.removed)
+	assertIds(t, "canceled", s.canceled)
+}
+
+func TestShipCompensatesAfterContextIsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := &shipServer{onGenerate: cancel}
+	c := newShipClient(t, s)
+
+	result, err := c.Ship(ctx, shipRequests("a", "b"), &ShipOptions{Compensate: true})
+	if !errors.Is(err, ErrShipmentIncomplete) {
+		t.Fatalf("expected ErrShipmentIncomplete, got %v", err)
+	}
+
+	for _, order := range result.Orders {
+		if !errors.Is(order.Err, context.Canceled) {
+			t.Fatalf("expected the generate step to be canceled, got %v", order.Err)
+		}
+		if !order.Compensated || order.CompensationErr != nil {
+			t.Fatalf("order not compensated after the caller gave up: %+v", order)
+		}
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	assertIds(t, "canceled", s.canceled, "order-a", "order-b")
+}
@@ -0,0 +1,62 @@
+package melhorenvio
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenStore persiste as credenciais fora do client, permitindo que vários processos
+// compartilhem a mesma conta do Melhor Envio sem que um invalide o refresh token do outro
+type TokenStore interface {
+	// retorna ErrCredentialsNotFound caso ainda não existam credenciais salvas
+	Load(ctx context.Context) (Credentials, error)
+	Save(ctx context.Context, credentials Credentials) error
+	// trava o store para atualização de token, a função retornada libera a trava
+	Lock(ctx context.Context) (func(), error)
+}
+
+type MemoryStore struct {
+	mutex       sync.Mutex
+	credentials *Credentials
+
+	lock chan struct{}
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		lock: make(chan struct{}, 1),
+	}
+}
+
+func (s *MemoryStore) Load(ctx context.Context) (Credentials, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.credentials == nil {
+		return Credentials{}, ErrCredentialsNotFound
+	}
+	return *s.credentials, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, credentials Credentials) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.credentials = &credentials
+	return nil
+}
+
+func (s *MemoryStore) Lock(ctx context.Context) (func(), error) {
+	select {
+	case s.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-s.lock
+		})
+	}, nil
+}
@@ -0,0 +1,722 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tempo que o remetente montado por CartFromStore é reaproveitado
+const storeSenderTTL = 10 * time.Minute
+
+// Store é uma loja cadastrada na conta, utilizada como remetente dos envios
+type Store struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Description   string `json:"description"`
+	CompanyName   string `json:"company_name"`
+	Document      string `json:"document"`
+	StateRegister string `json:"state_register"`
+	Website       string `json:"website"`
+	Protocol      string `json:"protocol"`
+	Status        string `json:"status"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
+}
+
+type StoreCountry struct {
+	Id      string `json:"id"`
+	Country string `json:"country"`
+}
+
+type StoreState struct {
+	Id        int32        `json:"id"`
+	State     string       `json:"state"`
+	StateAbbr string       `json:"state_abbr"`
+	Country   StoreCountry `json:"country"`
+}
+
+type StoreCity struct {
+	Id    int32      `json:"id"`
+	City  string     `json:"city"`
+	State StoreState `json:"state"`
+}
+
+// endereço de uma loja ou do cadastro do usuário
+type StoreAddress struct {
+	Id         int32     `json:"id"`
+	Label      string    `json:"label"`
+	PostalCode string    `json:"postal_code"`
+	Address    string    `json:"address"`
+	Number     string    `json:"number"`
+	Complement string    `json:"complement"`
+	District   string    `json:"district"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	City       StoreCity `json:"city"`
+}
+
+type StorePhone struct {
+	Id    int32  `json:"id"`
+	Label string `json:"label"`
+	Phone string `json:"phone"`
+	Type  string `json:"type"`
+}
+
+type StoreRequest struct {
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Description   string `json:"description,omitempty"`
+	CompanyName   string `json:"company_name"`
+	Document      string `json:"document"`
+	StateRegister string `json:"state_register,omitempty"`
+	Website       string `json:"website,omitempty"`
+}
+
+type AddressRequest struct {
+	Label      string `json:"label,omitempty"`
+	PostalCode string `json:"postal_code"`
+	Address    string `json:"address"`
+	Number     string `json:"number"`
+	Complement string `json:"complement,omitempty"`
+	District   string `json:"district"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	CountryId  string `json:"country_id,omitempty"`
+}
+
+type PhoneRequest struct {
+	Type  string `json:"type,omitempty"`
+	Phone string `json:"phone"`
+}
+
+type StoreError struct {
+	APIError
+}
+
+func (se *StoreError) Error() string {
+	return "melhor envio: store: " + se.describe()
+}
+
+func (se *StoreError) Unwrap() error {
+	return &se.APIError
+}
+
+func newStoreError(e *APIError) error {
+	return &StoreError{APIError: *e}
+}
+
+func storePath(storeId string) string {
+	return "/api/v2/me/companies/" + url.PathEscape(storeId)
+}
+
+// ListStores retorna todas as lojas da conta, percorrendo todas as páginas
+func (c *Client) ListStores(ctx context.Context) ([]*Store, error) {
+	var stores []*Store
+
+	it := newIterator(ctx, "/api/v2/me/companies", func(ctx context.Context, path string) (*Page[*Store], error) {
+		return fetchPage[*Store](ctx, c, path, newStoreError)
+	})
+	for it.Next() {
+		stores = append(stores, it.Value())
+	}
+	return stores, it.Err()
+}
+
+func (c *Client) GetStore(ctx context.Context, storeId string) (*Store, error) {
+	httpReq, err := c.newRequest(ctx, "GET", storePath(storeId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *Store
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) CreateStore(ctx context.Context, req *StoreRequest) (*Store, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/companies", req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *Store
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) UpdateStore(ctx context.Context, storeId string, req *StoreRequest) (*Store, error) {
+	httpReq, err := c.newRequest(ctx, "PUT", storePath(storeId), req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *Store
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) DeleteStore(ctx context.Context, storeId string) error {
+	httpReq, err := c.newRequest(ctx, "DELETE", storePath(storeId), nil)
+	if err != nil {
+		return err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	default:
+		return newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) ListStoreAddresses(ctx context.Context, storeId string) ([]*StoreAddress, error) {
+	httpReq, err := c.newRequest(ctx, "GET", storePath(storeId)+"/addresses", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp []*StoreAddress
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) CreateStoreAddress(ctx context.Context, storeId string, req *AddressRequest) (*StoreAddress, error) {
+	httpReq, err := c.newRequest(ctx, "POST", storePath(storeId)+"/addresses", req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *StoreAddress
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) UpdateStoreAddress(ctx context.Context, storeId string, addressId int32, req *AddressRequest) (*StoreAddress, error) {
+	httpReq, err := c.newRequest(ctx, "PUT", storePath(storeId)+"/addresses/"+strconv.FormatInt(int64(addressId), 10), req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *StoreAddress
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) DeleteStoreAddress(ctx context.Context, storeId string, addressId int32) error {
+	httpReq, err := c.newRequest(ctx, "DELETE", storePath(storeId)+"/addresses/"+strconv.FormatInt(int64(addressId), 10), nil)
+	if err != nil {
+		return err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	default:
+		return newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) ListStorePhones(ctx context.Context, storeId string) ([]*StorePhone, error) {
+	httpReq, err := c.newRequest(ctx, "GET", storePath(storeId)+"/phones", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp []*StorePhone
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) CreateStorePhone(ctx context.Context, storeId string, req *PhoneRequest) (*StorePhone, error) {
+	httpReq, err := c.newRequest(ctx, "POST", storePath(storeId)+"/phones", req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *StorePhone
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) UpdateStorePhone(ctx context.Context, storeId string, phoneId int32, req *PhoneRequest) (*StorePhone, error) {
+	httpReq, err := c.newRequest(ctx, "PUT", storePath(storeId)+"/phones/"+strconv.FormatInt(int64(phoneId), 10), req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *StorePhone
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) DeleteStorePhone(ctx context.Context, storeId string, phoneId int32) error {
+	httpReq, err := c.newRequest(ctx, "DELETE", storePath(storeId)+"/phones/"+strconv.FormatInt(int64(phoneId), 10), nil)
+	if err != nil {
+		return err
+	}
+
+	defer c.senders.invalidate(storeId)
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	default:
+		return newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+// ListAddresses retorna os endereços salvos no cadastro do usuário (fora das lojas)
+func (c *Client) ListAddresses(ctx context.Context) ([]*StoreAddress, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me/addresses", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp []*StoreAddress
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+// CreateAddress cadastra um endereço no cadastro do usuário (fora das lojas)
+func (c *Client) CreateAddress(ctx context.Context, req *AddressRequest) (*StoreAddress, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/addresses", req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *StoreAddress
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) UpdateAddress(ctx context.Context, addressId int32, req *AddressRequest) (*StoreAddress, error) {
+	httpReq, err := c.newRequest(ctx, "PUT", "/api/v2/me/addresses/"+strconv.FormatInt(int64(addressId), 10), req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var resp *StoreAddress
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: store: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+func (c *Client) DeleteAddress(ctx context.Context, addressId int32) error {
+	httpReq, err := c.newRequest(ctx, "DELETE", "/api/v2/me/addresses/"+strconv.FormatInt(int64(addressId), 10), nil)
+	if err != nil {
+		return err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrInvalidToken
+	default:
+		return newStoreError(parseAPIError(httpResp, body))
+	}
+}
+
+// CartFromStore monta o remetente de um AddToCartRequest a partir de uma loja,
+// utilizando o primeiro endereço e telefone cadastrados nela
+// o resultado é reaproveitado por storeSenderTTL (as alterações da loja feitas por este client o descartam),
+// evitando consultar a loja a cada AddToCart com FromStoreId
+func (c *Client) CartFromStore(ctx context.Context, storeId string) (CartToFrom, error) {
+	if from, ok := c.senders.get(storeId); ok {
+		return from, nil
+	}
+
+	from := CartToFrom{}
+
+	store, err := c.GetStore(ctx, storeId)
+	if err != nil {
+		return from, err
+	}
+
+	from.Name = store.Name
+	from.Email = store.Email
+	from.CompanyDocument = store.Document
+	from.StateRegister = store.StateRegister
+
+	addresses, err := c.ListStoreAddresses(ctx, storeId)
+	if err != nil {
+		return from, err
+	}
+	if len(addresses) > 0 {
+		address := addresses[0]
+		from.Address = address.Address
+		from.Number = address.Number
+		from.Complement = address.Complement
+		from.District = address.District
+		from.City = address.City.City
+		from.StateAbbr = address.City.State.StateAbbr
+		from.CountryId = address.City.State.Country.Id
+		from.PostalCode = address.PostalCode
+	}
+
+	phones, err := c.ListStorePhones(ctx, storeId)
+	if err != nil {
+		return from, err
+	}
+	if len(phones) > 0 {
+		from.Phone = phones[0].Phone
+	}
+
+	c.senders.set(storeId, from)
+	return from, nil
+}
+
+type cachedSender struct {
+	from      CartToFrom
+	expiresAt time.Time
+}
+
+// cache dos remetentes montados por CartFromStore, por id da loja
+type senderCache struct {
+	mutex   sync.Mutex
+	entries map[string]cachedSender
+}
+
+func (sc *senderCache) get(storeId string) (CartToFrom, bool) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	entry, ok := sc.entries[storeId]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return CartToFrom{}, false
+	}
+	return entry.from, true
+}
+
+func (sc *senderCache) set(storeId string, from CartToFrom) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if sc.entries == nil {
+		sc.entries = map[string]cachedSender{}
+	}
+	sc.entries[storeId] = cachedSender{from: from, expiresAt: time.Now().Add(storeSenderTTL)}
+}
+
+func (sc *senderCache) invalidate(storeId string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	delete(sc.entries, storeId)
+}
+
+// preenche os campos vazios de to com os valores de from
+func mergeCartToFrom(to CartToFrom, from CartToFrom) CartToFrom {
+	fields := []struct {
+		dst *string
+		src string
+	}{
+		{&to.Name, from.Name},
+		{&to.Phone, from.Phone},
+		{&to.Email, from.Email},
+		{&to.Document, from.Document},
+		{&to.CompanyDocument, from.CompanyDocument},
+		{&to.StateRegister, from.StateRegister},
+		{&to.Address, from.Address},
+		{&to.Complement, from.Complement},
+		{&to.Number, from.Number},
+		{&to.District, from.District},
+		{&to.City, from.City},
+		{&to.CountryId, from.CountryId},
+		{&to.PostalCode, from.PostalCode},
+		{&to.StateAbbr, from.StateAbbr},
+	}
+	for _, field := range fields {
+		if *field.dst == "" {
+			*field.dst = field.src
+		}
+	}
+	return to
+}
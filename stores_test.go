@@ -0,0 +1,121 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAddToCartFromStoreIsCached(t *testing.T) {
+	storeCalls := 0
+	var sent []CartToFrom
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/me/companies/store-1":
+			storeCalls++
+			w.Write([]byte(`{"id":"store-1","name":"Loja","document":"12345678000190"}`))
+		case "/api/v2/me/companies/store-1/addresses":
+			w.Write([]byte(`[{"postal_code":"01001000","address":"Praça da Sé","number":"1","city":{"city":"São Paulo","state":{"state_abbr":"SP","country":{"id":"BR"}}}}]`))
+		case "/api/v2/me/companies/store-1/phones":
+			w.Write([]byte(`[{"phone":"11999999999"}]`))
+		case "/api/v2/me/cart":
+			req := AddToCartRequest{}
+			json.NewDecoder(r.Body).Decode(&req)
+			sent = append(sent, req.From)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"order"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	c := newTestClient(srv, Config{})
+
+	for i := 0; i < 3; i++ {
+		_, err := c.AddToCart(&AddToCartRequest{FromStoreId: "store-1", From: CartToFrom{Name: "Expedição"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if storeCalls != 1 {
+		t.Fatalf("expected the store to be fetched once, got %d", storeCalls)
+	}
+	from := sent[2]
+	if from.Name != "Expedição" || from.CompanyDocument != "12345678000190" || from.CountryId != "BR" || from.Phone != "11999999999" {
+		t.Fatalf("unexpected sender: %+v", from)
+	}
+}
+
+func TestStoreChangesInvalidateSender(t *testing.T) {
+	storeCalls := 0
+	var requests []string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/me/companies/store-1":
+			storeCalls++
+			w.Write([]byte(`{"id":"store-1","name":"Loja"}`))
+		case "/api/v2/me/companies/store-1/addresses", "/api/v2/me/companies/store-1/phones":
+			w.Write([]byte(`[]`))
+		case "/api/v2/me/companies/store-1/addresses/7", "/api/v2/me/companies/store-1/phones/8", "/api/v2/me/addresses/9":
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			if r.Method == "DELETE" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			w.Write([]byte(`{"id":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	c := newTestClient(srv, Config{})
+	ctx := context.Background()
+
+	changes := []func() error{
+		func() error {
+			_, err := c.UpdateStoreAddress(ctx, "store-1", 7, &AddressRequest{PostalCode: "01001000"})
+			return err
+		},
+		func() error { return c.DeleteStoreAddress(ctx, "store-1", 7) },
+		func() error {
+			_, err := c.UpdateStorePhone(ctx, "store-1", 8, &PhoneRequest{Phone: "11999999999"})
+			return err
+		},
+		func() error { return c.DeleteStorePhone(ctx, "store-1", 8) },
+	}
+	for i, change := range changes {
+		if _, err := c.CartFromStore(ctx, "store-1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := change(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.CartFromStore(ctx, "store-1"); err != nil {
+			t.Fatal(err)
+		}
+		if storeCalls != i+2 {
+			t.Fatalf("change %d did not invalidate the sender, store fetched %d times", i, storeCalls)
+		}
+	}
+
+	// endereços do cadastro do usuário não fazem parte do remetente da loja
+	if _, err := c.UpdateAddress(ctx, 9, &AddressRequest{PostalCode: "01001000"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteAddress(ctx, 9); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"PUT /api/v2/me/companies/store-1/addresses/7",
+		"DELETE /api/v2/me/companies/store-1/addresses/7",
+		"PUT /api/v2/me/companies/store-1/phones/8",
+		"DELETE /api/v2/me/companies/store-1/phones/8",
+		"PUT /api/v2/me/addresses/9",
+		"DELETE /api/v2/me/addresses/9",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+}
@@ -0,0 +1,334 @@
+package melhorenvio
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTrackerInterval  = 5 * time.Minute
+	defaultTrackerBatchSize = 100
+	defaultTrackerRetention = 30 * 24 * time.Hour
+	trackerEventsBuffer     = 64
+	// falhas seguidas após as quais Run desiste, quando OnError não é informado
+	trackerMaxFailures = 5
+)
+
+// TrackerStore persiste o estado do Tracker, para que um reinício não emita novamente
+// eventos já processados
+type TrackerStore interface {
+	Load(ctx context.Context) (*TrackerState, error)
+	Save(ctx context.Context, state *TrackerState) error
+}
+
+type TrackerState struct {
+	// último status conhecido de cada etiqueta em acompanhamento
+	Statuses map[string]OrderStatus
+	// etiquetas que chegaram a um status final e quando, mantidas por TrackerConfig.Retention
+	// apenas para que um Add repetido não as volte a acompanhar
+	Finished map[string]time.Time
+}
+
+type StatusChange struct {
+	OrderId string
+	// vazio na primeira consulta da etiqueta
+	From     OrderStatus
+	To       OrderStatus
+	Tracking *Tracking
+}
+
+type TrackerConfig struct {
+	// intervalo entre as consultas (padrão de 5 minutos)
+	Interval time.Duration
+	// quantidade de etiquetas por requisição de rastreio (padrão de 100)
+	BatchSize int
+
+	Store TrackerStore
+	// por quanto tempo uma etiqueta em status final é lembrada para ignorar um Add repetido (padrão de 30 dias)
+	Retention time.Duration
+
+	// quando informado, os eventos são entregues aqui em vez do canal Events
+	// um erro interrompe a consulta, e o evento é emitido novamente na próxima
+	OnChange func(ctx context.Context, change StatusChange) error
+
+	// chamado a cada consulta que falhar durante o Run (ex: falha de rede), um erro retornado
+	// interrompe o Run. Quando não informado, Run retorna após trackerMaxFailures falhas seguidas
+	OnError func(ctx context.Context, err error) error
+}
+
+// Tracker consulta periodicamente o rastreio das etiquetas e emite um evento a cada mudança de status.
+// Etiquetas em status final (entregue, cancelada ou expirada) deixam de ser acompanhadas após o evento,
+// e um Add repetido delas é ignorado durante Retention
+type Tracker struct {
+	client *Client
+	config TrackerConfig
+
+	// serializa as consultas, para que duas chamadas de Poll em paralelo não emitam a mesma mudança
+	pollMutex sync.Mutex
+
+	mutex    sync.Mutex
+	statuses map[string]OrderStatus
+	finished map[string]time.Time
+	loaded   bool
+
+	events chan StatusChange
+}
+
+func NewTracker(client *Client, config TrackerConfig) *Tracker {
+	if config.Interval <= 0 {
+		config.Interval = defaultTrackerInterval
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultTrackerBatchSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultTrackerRetention
+	}
+
+	return &Tracker{
+		client:   client,
+		config:   config,
+		statuses: map[string]OrderStatus{},
+		finished: map[string]time.Time{},
+		events:   make(chan StatusChange, trackerEventsBuffer),
+	}
+}
+
+func IsTerminalStatus(status OrderStatus) bool {
+	switch status {
+	case OrderStatus_Delivered, OrderStatus_Canceled, OrderStatus_Expired:
+		return true
+	}
+	return false
+}
+
+// Events retorna o canal de eventos (não utilizado quando OnChange é informado)
+// o canal nunca é fechado, já que Poll pode ser chamado antes, depois ou em paralelo ao Run
+func (t *Tracker) Events() <-chan StatusChange {
+	return t.events
+}
+
+func (t *Tracker) Add(orderIds ...string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, orderId := range orderIds {
+		if _, ok := t.finished[orderId]; ok {
+			continue
+		}
+		if _, ok := t.statuses[orderId]; !ok {
+			t.statuses[orderId] = ""
+		}
+	}
+}
+
+func (t *Tracker) Remove(orderIds ...string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, orderId := range orderIds {
+		delete(t.statuses, orderId)
+		delete(t.finished, orderId)
+	}
+}
+
+// Run consulta o rastreio a cada Interval até o contexto ser cancelado
+// as falhas de consulta são repassadas ao OnError e tentadas novamente no próximo Interval
+func (t *Tracker) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.config.Interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		err := t.Poll(ctx)
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			failures++
+			if t.config.OnError != nil {
+				err = t.config.OnError(ctx, err)
+				if err != nil {
+					return err
+				}
+			} else if failures >= trackerMaxFailures {
+				return err
+			}
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll faz uma única rodada de consultas
+func (t *Tracker) Poll(ctx context.Context) error {
+	t.pollMutex.Lock()
+	defer t.pollMutex.Unlock()
+
+	err := t.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	pruned := false
+	for orderId, finishedAt := range t.finished {
+		if time.Since(finishedAt) > t.config.Retention {
+			delete(t.finished, orderId)
+			pruned = true
+		}
+	}
+	orderIds := make([]string, 0, len(t.statuses))
+	for orderId := range t.statuses {
+		orderIds = append(orderIds, orderId)
+	}
+	t.mutex.Unlock()
+	sort.Strings(orderIds)
+
+	if pruned {
+		err = t.save(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for start := 0; start < len(orderIds); start += t.config.BatchSize {
+		end := start + t.config.BatchSize
+		if end > len(orderIds) {
+			end = len(orderIds)
+		}
+
+		trackings, err := t.client.Track(ctx, orderIds[start:end])
+		if err != nil {
+			return err
+		}
+
+		err = t.apply(ctx, orderIds[start:end], trackings)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Tracker) apply(ctx context.Context, orderIds []string, trackings map[string]*Tracking) (err error) {
+	changed := false
+	defer func() {
+		if !changed {
+			return
+		}
+		if saveErr := t.save(ctx); err == nil {
+			err = saveErr
+		}
+	}()
+
+	for _, orderId := range orderIds {
+		tracking := trackings[orderId]
+		if tracking == nil {
+			continue
+		}
+
+		t.mutex.Lock()
+		previous, tracked := t.statuses[orderId]
+		t.mutex.Unlock()
+
+		if !tracked || previous == tracking.Status {
+			continue
+		}
+
+		err = t.emit(ctx, StatusChange{
+			OrderId:  orderId,
+			From:     previous,
+			To:       tracking.Status,
+			Tracking: tracking,
+		})
+		if err != nil {
+			return err
+		}
+
+		t.mutex.Lock()
+		if IsTerminalStatus(tracking.Status) {
+			delete(t.statuses, orderId)
+			t.finished[orderId] = time.Now()
+		} else {
+			t.statuses[orderId] = tracking.Status
+		}
+		t.mutex.Unlock()
+		changed = true
+	}
+
+	return nil
+}
+
+func (t *Tracker) emit(ctx context.Context, change StatusChange) error {
+	if t.config.OnChange != nil {
+		return t.config.OnChange(ctx, change)
+	}
+
+	select {
+	case t.events <- change:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracker) load(ctx context.Context) error {
+	t.mutex.Lock()
+	loaded := t.loaded
+	t.mutex.Unlock()
+
+	if loaded || t.config.Store == nil {
+		return nil
+	}
+
+	stored, err := t.config.Store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	if stored != nil {
+		for orderId, status := range stored.Statuses {
+			t.statuses[orderId] = status
+		}
+		for orderId, finishedAt := range stored.Finished {
+			delete(t.statuses, orderId)
+			t.finished[orderId] = finishedAt
+		}
+	}
+	t.loaded = true
+	t.mutex.Unlock()
+
+	return nil
+}
+
+func (t *Tracker) save(ctx context.Context) error {
+	if t.config.Store == nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	state := &TrackerState{
+		Statuses: make(map[string]OrderStatus, len(t.statuses)),
+		Finished: make(map[string]time.Time, len(t.finished)),
+	}
+	for orderId, status := range t.statuses {
+		state.Statuses[orderId] = status
+	}
+	for orderId, finishedAt := range t.finished {
+		state.Finished[orderId] = finishedAt
+	}
+	t.mutex.Unlock()
+
+	return t.config.Store.Save(ctx, state)
+}
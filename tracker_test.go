@@ -0,0 +1,207 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memoryTrackerStore struct {
+	mutex sync.Mutex
+	state *TrackerState
+}
+
+func (s *memoryTrackerStore) Load(ctx context.Context) (*TrackerState, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.state, nil
+}
+
+func (s *memoryTrackerStore) Save(ctx context.Context, state *TrackerState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.state = state
+	return nil
+}
+
+func newTrackingServer(t *testing.T, status OrderStatus) *Client {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		req := ordersRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		resp := map[string]*Tracking{}
+		for _, orderId := range req.Orders {
+			resp[orderId] = &Tracking{Id: orderId, Status: status}
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	return newTestClient(srv, Config{})
+}
+
+func TestTrackerForgetsTerminalOrders(t *testing.T) {
+	c := newTrackingServer(t, OrderStatus_Delivered)
+	store := &memoryTrackerStore{}
+
+	var changes []StatusChange
+	onChange := func(ctx context.Context, change StatusChange) error {
+		changes = append(changes, change)
+		return nil
+	}
+
+	tracker := NewTracker(c, TrackerConfig{Store: store, OnChange: onChange})
+	tracker.Add("order-1")
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].To != OrderStatus_Delivered {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if _, ok := store.state.Statuses["order-1"]; ok {
+		t.Fatalf("terminal order still tracked: %+v", store.state.Statuses)
+	}
+	if _, ok := store.state.Finished["order-1"]; !ok {
+		t.Fatalf("terminal order not recorded as finished: %+v", store.state.Finished)
+	}
+
+	// reinício, com o ERP adicionando novamente a mesma etiqueta
+	tracker = NewTracker(c, TrackerConfig{Store: store, OnChange: onChange})
+	tracker.Add("order-1")
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("terminal order replayed after restart: %+v", changes)
+	}
+}
+
+func TestTrackerPollAfterRun(t *testing.T) {
+	c := newTrackingServer(t, OrderStatus_Posted)
+	tracker := NewTracker(c, TrackerConfig{Interval: time.Hour})
+	tracker.Add("order-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tracker.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+
+	// não deve entrar em pânico enviando no canal de eventos
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	change := <-tracker.Events()
+	if change.To != OrderStatus_Posted {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+}
+
+func TestTrackerRunReportsErrors(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c := newTestClient(srv, Config{})
+
+	stop := errors.New("stop")
+	var reported []error
+	tracker := NewTracker(c, TrackerConfig{
+		Interval: time.Millisecond,
+		OnError: func(ctx context.Context, err error) error {
+			reported = append(reported, err)
+			if len(reported) == 2 {
+				return stop
+			}
+			return nil
+		},
+	})
+	tracker.Add("order-1")
+
+	if err := tracker.Run(context.Background()); err != stop {
+		t.Fatalf("expected the OnError result, got %v", err)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("expected 2 reported errors, got %d", len(reported))
+	}
+
+	// sem OnError, Run desiste após falhas seguidas
+	tracker = NewTracker(c, TrackerConfig{Interval: time.Millisecond})
+	tracker.Add("order-1")
+	if err := tracker.Run(context.Background()); err == nil {
+		t.Fatal("expected Run to return the tracking error")
+	}
+}
+
+func TestTrackerPrunesFinishedOrders(t *testing.T) {
+	c := newTrackingServer(t, OrderStatus_Delivered)
+	store := &memoryTrackerStore{state: &TrackerState{
+		Finished: map[string]time.Time{
+			"order-1": time.Now().Add(-2 * time.Hour),
+			"order-2": time.Now(),
+		},
+	}}
+
+	var changes []StatusChange
+	tracker := NewTracker(c, TrackerConfig{
+		Store:     store,
+		Retention: time.Hour,
+		OnChange: func(ctx context.Context, change StatusChange) error {
+			changes = append(changes, change)
+			return nil
+		},
+	})
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.state.Finished["order-1"]; ok || len(store.state.Finished) != 1 {
+		t.Fatalf("expected only order-2 to be kept: %+v", store.state.Finished)
+	}
+
+	// passada a retenção, a etiqueta volta a ser acompanhada
+	tracker.Add("order-1", "order-2")
+	if err := tracker.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].OrderId != "order-1" {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+}
+
+func TestTrackerConcurrentPollsEmitOnce(t *testing.T) {
+	c := newTrackingServer(t, OrderStatus_Posted)
+
+	var mutex sync.Mutex
+	var changes []StatusChange
+	tracker := NewTracker(c, TrackerConfig{
+		OnChange: func(ctx context.Context, change StatusChange) error {
+			// entrega lenta, para que as consultas em paralelo se sobreponham
+			time.Sleep(20 * time.Millisecond)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			changes = append(changes, change)
+			return nil
+		},
+	})
+	tracker.Add("order-1", "order-2")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := tracker.Poll(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(changes) != 2 {
+		t.Fatalf("expected one change per order, got %+v", changes)
+	}
+}
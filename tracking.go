@@ -0,0 +1,142 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type OrderStatus string
+
+const (
+	OrderStatus_Pending     OrderStatus = "pending"
+	OrderStatus_Released    OrderStatus = "released"
+	OrderStatus_Paid        OrderStatus = "paid"
+	OrderStatus_Generated   OrderStatus = "generated"
+	OrderStatus_Posted      OrderStatus = "posted"
+	OrderStatus_Delivered   OrderStatus = "delivered"
+	OrderStatus_Undelivered OrderStatus = "undelivered"
+	OrderStatus_Canceled    OrderStatus = "canceled"
+	OrderStatus_Expired     OrderStatus = "expired"
+)
+
+const apiTimeLayout = "2006-01-02 15:04:05"
+
+// a api retorna datas no horário de Brasília, sem informação de fuso
+var apiLocation = time.FixedZone("BRT", -3*60*60)
+
+// converte as datas da api, aceitando também RFC 3339, nulo ou vazio (data zero)
+func parseApiTime(value *string) (time.Time, error) {
+	if value == nil || *value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.ParseInLocation(apiTimeLayout, *value, apiLocation)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, *value)
+}
+
+type ordersRequest struct {
+	Orders []string `json:"orders"`
+}
+
+type Tracking struct {
+	Id                  string      `json:"id"`
+	Protocol            string      `json:"protocol"`
+	Status              OrderStatus `json:"status"`
+	Tracking            string      `json:"tracking"`
+	MelhorEnvioTracking string      `json:"melhorenvio_tracking"`
+	CreatedAt           time.Time   `json:"created_at"`
+	PaidAt              time.Time   `json:"paid_at"`
+	GeneratedAt         time.Time   `json:"generated_at"`
+	PostedAt            time.Time   `json:"posted_at"`
+	DeliveredAt         time.Time   `json:"delivered_at"`
+	CanceledAt          time.Time   `json:"canceled_at"`
+	ExpiredAt           time.Time   `json:"expired_at"`
+}
+
+func (t *Tracking) UnmarshalJSON(data []byte) error {
+	type tracking Tracking
+	aux := &struct {
+		*tracking
+		CreatedAt   *string `json:"created_at"`
+		PaidAt      *string `json:"paid_at"`
+		GeneratedAt *string `json:"generated_at"`
+		PostedAt    *string `json:"posted_at"`
+		DeliveredAt *string `json:"delivered_at"`
+		CanceledAt  *string `json:"canceled_at"`
+		ExpiredAt   *string `json:"expired_at"`
+	}{tracking: (*tracking)(t)}
+
+	err := json.Unmarshal(data, aux)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range []struct {
+		dst *time.Time
+		src *string
+	}{
+		{&t.CreatedAt, aux.CreatedAt},
+		{&t.PaidAt, aux.PaidAt},
+		{&t.GeneratedAt, aux.GeneratedAt},
+		{&t.PostedAt, aux.PostedAt},
+		{&t.DeliveredAt, aux.DeliveredAt},
+		{&t.CanceledAt, aux.CanceledAt},
+		{&t.ExpiredAt, aux.ExpiredAt},
+	} {
+		*field.dst, err = parseApiTime(field.src)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type TrackingError struct {
+	APIError
+}
+
+func (te *TrackingError) Error() string {
+	return "melhor envio: tracking: " + te.describe()
+}
+
+func (te *TrackingError) Unwrap() error {
+	return &te.APIError
+}
+
+// Track retorna o rastreio das etiquetas informadas, indexado pelo id da etiqueta
+func (c *Client) Track(ctx context.Context, orderIds []string) (map[string]*Tracking, error) {
+	httpReq, err := c.newRequest(ctx, "POST", "/api/v2/me/shipment/tracking", &ordersRequest{Orders: orderIds})
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp map[string]*Tracking
+		err = json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: tracking: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &TrackingError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
@@ -0,0 +1,107 @@
+package melhorenvio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type UserPhone struct {
+	Id    int32  `json:"id"`
+	Label string `json:"label"`
+	Phone string `json:"phone"`
+	Type  string `json:"type"`
+}
+
+type UserLimits struct {
+	Shipments          int32 `json:"shipments"`
+	ShipmentsAvailable int32 `json:"shipments_available"`
+}
+
+type User struct {
+	Id               string     `json:"id"`
+	Protocol         string     `json:"protocol"`
+	Firstname        string     `json:"firstname"`
+	Lastname         string     `json:"lastname"`
+	Email            string     `json:"email"`
+	Picture          string     `json:"picture"`
+	Thumbnail        string     `json:"thumbnail"`
+	Document         string     `json:"document"`
+	CompanyDocument  string     `json:"company_document"`
+	Birthdate        string     `json:"birthdate"`
+	EmailConfirmedAt string     `json:"email_confirmed_at"`
+	Phone            *UserPhone `json:"phone"`
+	Limits           UserLimits `json:"limits"`
+	AccessAt         string     `json:"access_at"`
+	CreatedAt        string     `json:"created_at"`
+	UpdatedAt        string     `json:"updated_at"`
+}
+
+type UserError struct {
+	APIError
+}
+
+func (ue *UserError) Error() string {
+	return "melhor envio: user: " + ue.describe()
+}
+
+func (ue *UserError) Unwrap() error {
+	return &ue.APIError
+}
+
+// Me retorna o perfil da conta dona das credenciais do client
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.doRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	return parseUserResponse(httpResp)
+}
+
+// busca o perfil com um token específico, sem passar pelo gerenciamento de credenciais
+// utilizado durante a autenticação, quando o token ainda não foi disponibilizado para as demais chamadas
+func (c *Client) fetchUser(ctx context.Context, accessToken string) (*User, error) {
+	httpReq, err := c.newRequest(ctx, "GET", "/api/v2/me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.injectDefaultHeaders(httpReq)
+	httpReq.Header.Set("Authorization", "Bearer "+accessToken)
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	return parseUserResponse(httpResp)
+}
+
+func parseUserResponse(httpResp *http.Response) (*User, error) {
+	body, _ := io.ReadAll(httpResp.Body)
+
+	switch httpResp.StatusCode {
+	case http.StatusOK:
+		var resp *User
+		err := json.Unmarshal(body, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("melhor envio: user: unrecognized response: %v %v", httpResp.StatusCode, string(body))
+		}
+
+		return resp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidToken
+	default:
+		return nil, &UserError{APIError: *parseAPIError(httpResp, body)}
+	}
+}
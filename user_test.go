@@ -0,0 +1,56 @@
+package melhorenvio
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newUserServer(t *testing.T, meStatus int) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth/token":
+			w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","expires_in":3600}`))
+		case "/api/v2/me":
+			w.WriteHeader(meStatus)
+			if meStatus == http.StatusOK {
+				w.Write([]byte(`{"id":"user-1","firstname":"Maria"}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestExchangeCodeFetchesUser(t *testing.T) {
+	srv := newUserServer(t, http.StatusOK)
+	c := NewClient(context.Background(), Config{ApiUrl: srv.URL, FetchUserOnAuthenticate: true})
+
+	credentials, err := c.ExchangeCode(context.Background(), "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.User == nil || credentials.User.Id != "user-1" {
+		t.Fatalf("unexpected user: %+v", credentials.User)
+	}
+}
+
+func TestExchangeCodeKeepsCredentialsWhenUserLookupFails(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusInternalServerError} {
+		srv := newUserServer(t, status)
+		c := NewClient(context.Background(), Config{ApiUrl: srv.URL, FetchUserOnAuthenticate: true})
+
+		credentials, err := c.ExchangeCode(context.Background(), "code")
+		if err != nil {
+			t.Fatalf("%d: exchanged credentials discarded: %v", status, err)
+		}
+		if credentials.AccessToken != "access" || credentials.RefreshToken != "refresh" || credentials.User != nil {
+			t.Fatalf("%d: unexpected credentials: %+v", status, credentials)
+		}
+	}
+}
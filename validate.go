@@ -0,0 +1,149 @@
+package melhorenvio
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Violation struct {
+	// caminho do campo no json do request, ex: "volumes[0].weight"
+	Field   string
+	Message string
+}
+
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (ve *ValidationError) Error() string {
+	messages := make([]string, len(ve.Violations))
+	for i, v := range ve.Violations {
+		messages[i] = v.Field + ": " + v.Message
+	}
+	return "melhor envio: validation: " + strings.Join(messages, "; ")
+}
+
+func (ve *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+// Validate verifica o request contra as restrições do serviço (peso, dimensões, soma dos lados
+// e valor segurado), retornando um *ValidationError com as violações de cada campo.
+// Cada volume é verificado contra as restrições do seu formato (Volume.Format, caixa quando vazio).
+// Nos produtos de uma cotação apenas os limites máximos do formato caixa são verificados, já que o
+// Melhor Envio monta o pacote final. Retorna ErrNilArgument caso service ou req seja nil
+func Validate[T *CotacaoRequest | *AddToCartRequest](service *Service, req T) error {
+	if service == nil {
+		return ErrNilArgument
+	}
+
+	v := &validator{}
+	restrictions := service.Restrictions
+
+	switch r := interface{}(req).(type) {
+	case *CotacaoRequest:
+		if r == nil {
+			return ErrNilArgument
+		}
+
+		for i, volume := range r.Volumes {
+			v.checkVolume(fmt.Sprintf("volumes[%d]", i), &restrictions.Formats, volume.Format, volume.Dimensions, volume.Diameter, volume.Weight)
+		}
+
+		insurance := r.Options.InsuranceValue
+		if len(r.Volumes) == 0 {
+			insurance = 0
+			for i, product := range r.Products {
+				v.checkBox(fmt.Sprintf("products[%d]", i), &restrictions.Formats.Box, product.Dimensions, product.Weight, false)
+				insurance += product.InsuranceValue * float64(product.Quantity)
+			}
+		}
+		v.checkInsurance("options.insurance_value", &restrictions.InsuranceValue, insurance)
+
+	case *AddToCartRequest:
+		if r == nil {
+			return ErrNilArgument
+		}
+
+		for i, volume := range r.Volumes {
+			v.checkVolume(fmt.Sprintf("volumes[%d]", i), &restrictions.Formats, volume.Format, volume.Dimensions, volume.Diameter, volume.Weight)
+		}
+		v.checkInsurance("options.insurance_value", &restrictions.InsuranceValue, r.Options.InsuranceValue)
+	}
+
+	return v.err()
+}
+
+func (v *validator) checkVolume(field string, formats *Formats, format PackageFormat, dimensions Dimensions, diameter float64, weight float64) {
+	switch format {
+	case "", PackageFormat_Box:
+		v.checkBox(field, &formats.Box, dimensions, weight, true)
+	case PackageFormat_Roll:
+		v.checkRoll(field, &formats.Roll, dimensions, diameter, weight)
+	case PackageFormat_Letter:
+		v.checkLetter(field, &formats.Letter, dimensions, weight)
+	default:
+		v.add(field+".format", "unknown format %q", format)
+	}
+}
+
+func (v *validator) checkBox(field string, box *Box, dimensions Dimensions, weight float64, checkMin bool) {
+	v.checkRange(field+".weight", box.Weight, weight, "kg", checkMin)
+	v.checkRange(field+".width", box.Width, dimensions.Width, "cm", checkMin)
+	v.checkRange(field+".height", box.Height, dimensions.Height, "cm", checkMin)
+	v.checkRange(field+".length", box.Length, dimensions.Length, "cm", checkMin)
+
+	sum := dimensions.Width + dimensions.Height + dimensions.Length
+	if box.Sum > 0 && sum > float64(box.Sum) {
+		v.add(field, "sum of dimensions %v cm exceeds maximum of %v cm", sum, box.Sum)
+	}
+}
+
+// no rolo a soma considera o comprimento mais duas vezes o diâmetro
+func (v *validator) checkRoll(field string, roll *Roll, dimensions Dimensions, diameter float64, weight float64) {
+	v.checkRange(field+".weight", roll.Weight, weight, "kg", true)
+	v.checkRange(field+".diameter", roll.Diameter, diameter, "cm", true)
+	v.checkRange(field+".length", roll.Length, dimensions.Length, "cm", true)
+
+	sum := dimensions.Length + 2*diameter
+	if roll.Sum > 0 && sum > float64(roll.Sum) {
+		v.add(field, "length plus twice the diameter %v cm exceeds maximum of %v cm", sum, roll.Sum)
+	}
+}
+
+// envelopes não têm altura
+func (v *validator) checkLetter(field string, letter *Letter, dimensions Dimensions, weight float64) {
+	v.checkRange(field+".weight", letter.Weight, weight, "kg", true)
+	v.checkRange(field+".width", letter.Width, dimensions.Width, "cm", true)
+	v.checkRange(field+".length", letter.Length, dimensions.Length, "cm", true)
+}
+
+func (v *validator) checkRange(field string, limits MinMax, value float64, unit string, checkMin bool) {
+	if limits.Max > 0 && value > limits.Max {
+		v.add(field, "%v %s exceeds maximum of %v %s", value, unit, limits.Max, unit)
+	}
+	if checkMin && value < limits.Min {
+		v.add(field, "%v %s is below minimum of %v %s", value, unit, limits.Min, unit)
+	}
+}
+
+func (v *validator) checkInsurance(field string, limits *MinMaxMaxDec, value float64) {
+	if limits.Max > 0 && value > limits.Max {
+		v.add(field, "%v exceeds maximum insurance value of %v", value, limits.Max)
+	}
+}
@@ -0,0 +1,72 @@
+package melhorenvio
+
+import (
+	"errors"
+	"testing"
+)
+
+var testService = &Service{
+	Restrictions: Restrictions{
+		InsuranceValue: MinMaxMaxDec{Max: 1000},
+		Formats: Formats{
+			Box:    Box{Weight: MinMax{Min: 0.1, Max: 30}, Width: MinMax{Min: 11, Max: 100}, Height: MinMax{Min: 2, Max: 100}, Length: MinMax{Min: 16, Max: 100}, Sum: 200},
+			Roll:   Roll{Weight: MinMax{Min: 0.1, Max: 30}, Diameter: MinMax{Min: 5, Max: 91}, Length: MinMax{Min: 18, Max: 100}, Sum: 200},
+			Letter: Letter{Weight: MinMax{Min: 0.01, Max: 0.5}, Width: MinMax{Min: 11, Max: 60}, Length: MinMax{Min: 16, Max: 60}},
+		},
+	},
+}
+
+func violationFields(err error) []string {
+	ve := &ValidationError{}
+	if !errors.As(err, &ve) {
+		return nil
+	}
+	fields := make([]string, len(ve.Violations))
+	for i, violation := range ve.Violations {
+		fields[i] = violation.Field
+	}
+	return fields
+}
+
+func TestValidateFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		volume   CartVolume
+		expected []string
+	}{
+		{"box", CartVolume{Dimensions: Dimensions{Width: 20, Height: 10, Length: 30}, Weight: 1}, nil},
+		{"box too heavy", CartVolume{Dimensions: Dimensions{Width: 20, Height: 10, Length: 30}, Weight: 31}, []string{"volumes[0].weight"}},
+		// sem altura, inválido como caixa mas válido como envelope
+		{"letter", CartVolume{Format: PackageFormat_Letter, Dimensions: Dimensions{Width: 20, Length: 30}, Weight: 0.2}, nil},
+		{"letter too heavy", CartVolume{Format: PackageFormat_Letter, Dimensions: Dimensions{Width: 20, Length: 30}, Weight: 1}, []string{"volumes[0].weight"}},
+		{"roll", CartVolume{Format: PackageFormat_Roll, Dimensions: Dimensions{Length: 90}, Diameter: 10, Weight: 1}, nil},
+		{"roll too thick", CartVolume{Format: PackageFormat_Roll, Dimensions: Dimensions{Length: 90}, Diameter: 95, Weight: 1}, []string{"volumes[0].diameter", "volumes[0]"}},
+		{"unknown", CartVolume{Format: "tube", Weight: 1}, []string{"volumes[0].format"}},
+	}
+
+	for _, test := range tests {
+		err := Validate(testService, &AddToCartRequest{Volumes: []CartVolume{test.volume}})
+		fields := violationFields(err)
+		if len(fields) != len(test.expected) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, err)
+			continue
+		}
+		for i := range fields {
+			if fields[i] != test.expected[i] {
+				t.Errorf("%v: expected %v, got %v", test.name, test.expected, fields)
+			}
+		}
+	}
+}
+
+func TestValidateNilArguments(t *testing.T) {
+	if err := Validate(nil, &CotacaoRequest{}); !errors.Is(err, ErrNilArgument) {
+		t.Fatalf("expected ErrNilArgument for nil service, got %v", err)
+	}
+	if err := Validate(testService, (*CotacaoRequest)(nil)); !errors.Is(err, ErrNilArgument) {
+		t.Fatalf("expected ErrNilArgument for nil request, got %v", err)
+	}
+	if err := Validate(testService, (*AddToCartRequest)(nil)); !errors.Is(err, ErrNilArgument) {
+		t.Fatalf("expected ErrNilArgument for nil request, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package melhorenvio
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+const (
+	WebhookSignatureHeader = "X-ME-Signature"
+
+	webhookMaxBodySize = 1 << 20
+)
+
+type EventType string
+
+const (
+	Event_OrderCreated     EventType = "order.created"
+	Event_OrderPending     EventType = "order.pending"
+	Event_OrderReleased    EventType = "order.released"
+	Event_OrderGenerated   EventType = "order.generated"
+	Event_OrderReceived    EventType = "order.received"
+	Event_OrderPosted      EventType = "order.posted"
+	Event_OrderDelivered   EventType = "order.delivered"
+	Event_OrderCancelled   EventType = "order.cancelled"
+	Event_OrderUndelivered EventType = "order.undelivered"
+	Event_OrderPaused      EventType = "order.paused"
+	Event_OrderSuspended   EventType = "order.suspended"
+)
+
+type WebhookTag struct {
+	Tag string `json:"tag"`
+	Url string `json:"url"`
+}
+
+// WebhookOrder é a etiqueta enviada no evento, com os mesmos dados do rastreio (Track)
+type WebhookOrder struct {
+	Tracking
+	UserId       string       `json:"user_id"`
+	SelfTracking string       `json:"self_tracking"`
+	TrackingUrl  string       `json:"tracking_url"`
+	Tags         []WebhookTag `json:"tags"`
+}
+
+func (o *WebhookOrder) UnmarshalJSON(data []byte) error {
+	err := json.Unmarshal(data, &o.Tracking)
+	if err != nil {
+		return err
+	}
+
+	extra := &struct {
+		UserId       string       `json:"user_id"`
+		SelfTracking string       `json:"self_tracking"`
+		TrackingUrl  string       `json:"tracking_url"`
+		Tags         []WebhookTag `json:"tags"`
+	}{}
+	err = json.Unmarshal(data, extra)
+	if err != nil {
+		return err
+	}
+
+	o.UserId = extra.UserId
+	o.SelfTracking = extra.SelfTracking
+	o.TrackingUrl = extra.TrackingUrl
+	o.Tags = extra.Tags
+	return nil
+}
+
+type WebhookEvent struct {
+	Event EventType    `json:"event"`
+	Data  WebhookOrder `json:"data"`
+}
+
+type WebhookCallback = func(ctx context.Context, event *WebhookEvent) error
+
+// WebhookHandler recebe as notificações do Melhor Envio, valida a assinatura e repassa
+// o evento para os callbacks registrados. Um erro em um callback resulta em 500, para que
+// o Melhor Envio tente reenviar a notificação
+type WebhookHandler struct {
+	secret string
+
+	mutex     sync.RWMutex
+	callbacks map[EventType][]WebhookCallback
+	any       []WebhookCallback
+}
+
+// NewWebhookHandler retorna ErrEmptyWebhookSecret caso secret seja vazio, já que qualquer um
+// conseguiria gerar uma assinatura válida com a chave vazia
+func NewWebhookHandler(secret string) (*WebhookHandler, error) {
+	if secret == "" {
+		return nil, ErrEmptyWebhookSecret
+	}
+
+	return &WebhookHandler{
+		secret:    secret,
+		callbacks: map[EventType][]WebhookCallback{},
+	}, nil
+}
+
+// NewWebhookHandler cria o handler validando as assinaturas com o ClientSecret do client
+func (c *Client) NewWebhookHandler() (*WebhookHandler, error) {
+	return NewWebhookHandler(c.config.Credentials.ClientSecret)
+}
+
+func (h *WebhookHandler) On(event EventType, callback WebhookCallback) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.callbacks[event] = append(h.callbacks[event], callback)
+}
+
+// OnAny registra um callback executado para todos os eventos
+func (h *WebhookHandler) OnAny(callback WebhookCallback) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.any = append(h.any, callback)
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !VerifyWebhookSignature(h.secret, body, r.Header.Get(WebhookSignatureHeader)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	event := &WebhookEvent{}
+	err = json.Unmarshal(body, event)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = h.Dispatch(r.Context(), event)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Dispatch executa os callbacks registrados para o evento, parando no primeiro erro
+func (h *WebhookHandler) Dispatch(ctx context.Context, event *WebhookEvent) error {
+	h.mutex.RLock()
+	callbacks := append([]WebhookCallback{}, h.callbacks[event.Event]...)
+	callbacks = append(callbacks, h.any...)
+	h.mutex.RUnlock()
+
+	for _, callback := range callbacks {
+		err := callback(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyWebhookSignature valida o HMAC-SHA256 do body, enviado em base64 no header X-ME-Signature
+// nenhuma assinatura é válida com secret vazio
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+
+	expected := SignWebhook(secret, body)
+	if hmac.Equal([]byte(signature), []byte(expected)) {
+		return true
+	}
+
+	// aceita também a assinatura em hexadecimal
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac, _ := base64.StdEncoding.DecodeString(expected)
+	return hmac.Equal(decoded, mac)
+}
+
+// SignWebhook gera a assinatura de um body, útil para simular notificações localmente
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
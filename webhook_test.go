@@ -0,0 +1,99 @@
+package melhorenvio
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const webhookPayload = `{"event":"order.posted","data":{"id":"order-1","protocol":"ORD-1","status":"posted","tracking":"AA123BR","posted_at":"2024-03-01 10:30:00","user_id":"7","tags":[{"tag":"pedido-42","url":null}]}}`
+
+func postWebhook(handler http.Handler, body string, signature string) int {
+	r := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	if signature != "" {
+		r.Header.Set(WebhookSignatureHeader, signature)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestWebhookHandlerSignedPayload(t *testing.T) {
+	handler, err := NewWebhookHandler("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received *WebhookEvent
+	handler.On(Event_OrderPosted, func(ctx context.Context, event *WebhookEvent) error {
+		received = event
+		return nil
+	})
+
+	code := postWebhook(handler, webhookPayload, SignWebhook("secret", []byte(webhookPayload)))
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if received == nil || received.Data.Id != "order-1" || received.Data.Status != OrderStatus_Posted ||
+		received.Data.PostedAt.IsZero() || len(received.Data.Tags) != 1 || received.Data.Tags[0].Tag != "pedido-42" {
+		t.Fatalf("unexpected event: %+v", received)
+	}
+
+	// assinatura em hexadecimal
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(webhookPayload))
+	if code := postWebhook(handler, webhookPayload, hex.EncodeToString(mac.Sum(nil))); code != http.StatusOK {
+		t.Fatalf("expected 200 with hex signature, got %d", code)
+	}
+}
+
+func TestWebhookHandlerRejectsInvalidSignature(t *testing.T) {
+	handler, err := NewWebhookHandler("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler.OnAny(func(ctx context.Context, event *WebhookEvent) error {
+		t.Error("callback called for an invalid signature")
+		return nil
+	})
+
+	tests := map[string]string{
+		"missing":    "",
+		"wrong key":  SignWebhook("other", []byte(webhookPayload)),
+		"empty key":  SignWebhook("", []byte(webhookPayload)),
+		"tampered":   SignWebhook("secret", []byte(webhookPayload+" ")),
+		"not base64": "not a signature",
+	}
+	for name, signature := range tests {
+		if code := postWebhook(handler, webhookPayload, signature); code != http.StatusUnauthorized {
+			t.Errorf("%v: expected 401, got %d", name, code)
+		}
+	}
+}
+
+func TestWebhookHandlerCallbackError(t *testing.T) {
+	handler, _ := NewWebhookHandler("secret")
+	handler.OnAny(func(ctx context.Context, event *WebhookEvent) error {
+		return errors.New("database unavailable")
+	})
+
+	// 500 para que o Melhor Envio reenvie a notificação
+	if code := postWebhook(handler, webhookPayload, SignWebhook("secret", []byte(webhookPayload))); code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", code)
+	}
+}
+
+func TestWebhookEmptySecret(t *testing.T) {
+	if _, err := NewWebhookHandler(""); !errors.Is(err, ErrEmptyWebhookSecret) {
+		t.Fatalf("expected ErrEmptyWebhookSecret, got %v", err)
+	}
+	if VerifyWebhookSignature("", []byte(webhookPayload), SignWebhook("", []byte(webhookPayload))) {
+		t.Fatal("signature accepted with an empty secret")
+	}
+}